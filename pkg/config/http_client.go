@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+// HTTPClient - настройки HTTP-клиента для обращения к другим сервисам.
+type HTTPClient struct {
+	Timeout             time.Duration `env:"HTTP_CLIENT_TIMEOUT" envDefault:"30s"`
+	MaxIdleConns        int           `env:"HTTP_CLIENT_MAX_IDLE_CONNS" envDefault:"100"`
+	MaxIdleConnsPerHost int           `env:"HTTP_CLIENT_MAX_IDLE_CONNS_PER_HOST" envDefault:"10"`
+	MaxConnsPerHost     int           `env:"HTTP_CLIENT_MAX_CONNS_PER_HOST" envDefault:"0"`
+	IdleConnTimeout     time.Duration `env:"HTTP_CLIENT_IDLE_CONN_TIMEOUT" envDefault:"90s"`
+	KeepAlive           time.Duration `env:"HTTP_CLIENT_KEEP_ALIVE" envDefault:"30s"`
+	ProxyURL            string        `env:"HTTP_CLIENT_PROXY_URL"`
+	UserAgent           string        `env:"HTTP_CLIENT_USER_AGENT"`
+	TLSCAFile           string        `env:"HTTP_CLIENT_TLS_CA_FILE"`
+	TLSCertFile         string        `env:"HTTP_CLIENT_TLS_CERT_FILE"`
+	TLSKeyFile          string        `env:"HTTP_CLIENT_TLS_KEY_FILE"`
+	TLSInsecure         bool          `env:"HTTP_CLIENT_TLS_INSECURE" envDefault:"false"`
+}
@@ -0,0 +1,315 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+
+	"github.com/mlplabs/common-go-pkg/pkg/config"
+)
+
+const (
+	// APIKeyHeader - заголовок ключа API, см. config.Auth.ApiKey.
+	APIKeyHeader = "X-API-KEY"
+
+	// за сколько до истечения обновлять токен, но не раньше половины срока жизни
+	tokenRefreshSkew = 30 * time.Second
+	// срок жизни токена OAuth2, если сервер не вернул expires_in
+	defaultTokenTTL = 5 * time.Minute
+	// таймаут запроса токена OAuth2, не зависящий от контекста вызвавшего его запроса
+	tokenFetchTimeout = 30 * time.Second
+)
+
+// ErrNoToken - в контексте нет токена входящего запроса.
+var ErrNoToken = errors.New("no token in context")
+
+type tokenKey struct{}
+
+// refreshTime - когда обновлять токен, выданный в issued на срок ttl.
+// Для коротких токенов запас сокращается до половины срока, иначе токен обновлялся бы при каждом запросе.
+func refreshTime(issued time.Time, ttl time.Duration) time.Time {
+	return issued.Add(ttl - min(tokenRefreshSkew, ttl/2))
+}
+
+// ContextWithToken сохраняет токен входящего запроса для пересылки (ProxyToken, ForwardToken).
+func ContextWithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// TokenFromContext возвращает токен, сохранённый ContextWithToken
+// или, для совместимости, под строковым ключом Token.
+func TokenFromContext(ctx context.Context) (string, bool) {
+	if token, ok := ctx.Value(tokenKey{}).(string); ok {
+		return token, true
+	}
+	token, ok := ctx.Value(Token).(string)
+	return token, ok
+}
+
+// CaptureToken - серверная middleware, сохраняющая токен из Authorization в контекст.
+func CaptureToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			ctx = ContextWithToken(ctx, auth)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Credentials - способ авторизации исходящих запросов.
+type Credentials interface {
+	Apply(req *http.Request) error
+}
+
+// CredentialsFunc - функция как Credentials.
+type CredentialsFunc func(req *http.Request) error
+
+func (f CredentialsFunc) Apply(req *http.Request) error {
+	return f(req)
+}
+
+// WithCredentials - авторизация всех запросов клиента. RequestParams.Credentials её переопределяет.
+func WithCredentials(creds Credentials) Option {
+	return func(c *Client) {
+		c.credentials = creds
+	}
+}
+
+// APIKey - статический ключ в заголовке header.
+func APIKey(header, key string) Credentials {
+	return CredentialsFunc(func(req *http.Request) error {
+		req.Header.Set(header, key)
+		return nil
+	})
+}
+
+// APIKeyFromConfig - ключ config.Auth.ApiKey в заголовке X-API-KEY.
+func APIKeyFromConfig(cfg *config.Auth) Credentials {
+	return APIKey(APIKeyHeader, cfg.ApiKey)
+}
+
+// ForwardToken - пересылка токена входящего запроса (см. CaptureToken, ContextWithToken).
+func ForwardToken() Credentials {
+	return CredentialsFunc(func(req *http.Request) error {
+		token, ok := TokenFromContext(req.Context())
+		if !ok {
+			return ErrNoToken
+		}
+		req.Header.Set("Authorization", bearer(token))
+		return nil
+	})
+}
+
+// ServiceJWTConfig - настройки токена, которым сервис подписывает свои запросы.
+type ServiceJWTConfig struct {
+	Issuer   string // имя сервиса
+	Audience string // сервис-получатель
+	Subject  string
+	Method   jwt.SigningMethod // RS256, ES256, EdDSA...
+	Key      interface{}       // *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey
+	KeyID    string            // заголовок kid
+	TTL      time.Duration
+}
+
+type serviceJWT struct {
+	cfg       ServiceJWTConfig
+	mu        sync.Mutex
+	tok       string
+	refreshAt time.Time
+}
+
+// ServiceJWT - короткоживущий JWT, выпускаемый самим сервисом. Токен переиспользуется до истечения.
+func ServiceJWT(cfg ServiceJWTConfig) Credentials {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 5 * time.Minute
+	}
+	return &serviceJWT{cfg: cfg}
+}
+
+func (s *serviceJWT) Apply(req *http.Request) error {
+	token, err := s.token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (s *serviceJWT) token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.tok != "" && now.Before(s.refreshAt) {
+		return s.tok, nil
+	}
+
+	exp := now.Add(s.cfg.TTL)
+	claims := jwt.StandardClaims{
+		Id:        uuid.NewString(),
+		Issuer:    s.cfg.Issuer,
+		Audience:  s.cfg.Audience,
+		Subject:   s.cfg.Subject,
+		IssuedAt:  now.Unix(),
+		NotBefore: now.Unix(),
+		ExpiresAt: exp.Unix(),
+	}
+	t := jwt.NewWithClaims(s.cfg.Method, claims)
+	if s.cfg.KeyID != "" {
+		t.Header["kid"] = s.cfg.KeyID
+	}
+	signed, err := t.SignedString(s.cfg.Key)
+	if err != nil {
+		return "", fmt.Errorf("client: sign service token: %w", err)
+	}
+	s.tok, s.refreshAt = signed, refreshTime(now, s.cfg.TTL)
+	return signed, nil
+}
+
+// ClientCredentialsConfig - настройки OAuth2 client credentials.
+type ClientCredentialsConfig struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	Audience     string        // необязательный параметр audience
+	HTTPClient   *http.Client  // по умолчанию http.DefaultClient
+	DefaultTTL   time.Duration // срок жизни токена без expires_in, по умолчанию 5 минут
+}
+
+type clientCredentials struct {
+	cfg       ClientCredentialsConfig
+	mu        sync.Mutex
+	tok       string
+	refreshAt time.Time
+	fetch     *tokenFetch // текущий запрос токена, общий для всех ожидающих
+}
+
+type tokenFetch struct {
+	done chan struct{}
+	tok  string
+	err  error
+}
+
+// ClientCredentials - токен OAuth2 по client credentials, кешируется и обновляется заранее до истечения.
+// Одновременные запросы ждут один общий запрос токена, каждый не дольше своего контекста.
+func ClientCredentials(cfg ClientCredentialsConfig) Credentials {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.DefaultTTL <= 0 {
+		cfg.DefaultTTL = defaultTokenTTL
+	}
+	return &clientCredentials{cfg: cfg}
+}
+
+func (cc *clientCredentials) Apply(req *http.Request) error {
+	token, err := cc.token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (cc *clientCredentials) token(ctx context.Context) (string, error) {
+	cc.mu.Lock()
+	if cc.tok != "" && time.Now().Before(cc.refreshAt) {
+		tok := cc.tok
+		cc.mu.Unlock()
+		return tok, nil
+	}
+	f := cc.fetch
+	if f == nil {
+		f = &tokenFetch{done: make(chan struct{})}
+		cc.fetch = f
+		// запрос не должен прерываться отменой контекста первого из ожидающих
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tokenFetchTimeout)
+		go func() {
+			defer cancel()
+			cc.run(fetchCtx, f)
+		}()
+	}
+	cc.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.tok, f.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (cc *clientCredentials) run(ctx context.Context, f *tokenFetch) {
+	issued := time.Now()
+	tok, ttl, err := cc.request(ctx)
+
+	cc.mu.Lock()
+	if err == nil {
+		cc.tok, cc.refreshAt = tok, refreshTime(issued, ttl)
+	}
+	cc.fetch = nil
+	cc.mu.Unlock()
+
+	f.tok, f.err = tok, err
+	close(f.done)
+}
+
+// request запрашивает новый токен и его срок жизни.
+func (cc *clientCredentials) request(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(cc.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cc.cfg.Scopes, " "))
+	}
+	if cc.cfg.Audience != "" {
+		form.Set("audience", cc.cfg.Audience)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cc.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(cc.cfg.ClientID), url.QueryEscape(cc.cfg.ClientSecret))
+
+	resp, err := cc.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("client: oauth2 token request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("client: oauth2 token request: status %d: %s", resp.StatusCode, body)
+	}
+
+	var tr struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return "", 0, fmt.Errorf("client: oauth2 token response: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return "", 0, fmt.Errorf("client: oauth2 token response: empty access_token")
+	}
+
+	ttl := time.Duration(tr.ExpiresIn) * time.Second
+	if ttl <= 0 {
+		ttl = cc.cfg.DefaultTTL
+	}
+	return tr.AccessToken, ttl, nil
+}
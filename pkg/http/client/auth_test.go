@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenServer issues numbered OAuth2 tokens with expires_in, each response waits for release.
+type tokenServer struct {
+	*httptest.Server
+	hits    atomic.Int64
+	release chan struct{}
+}
+
+func newTokenServer(t *testing.T, expiresIn int, release chan struct{}) *tokenServer {
+	if release == nil {
+		release = make(chan struct{})
+		close(release)
+	}
+	s := &tokenServer{release: release}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := s.hits.Add(1)
+		<-s.release
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":%d}`, n, expiresIn)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func applyToken(ctx context.Context, creds Credentials) (string, error) {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://svc/", nil)
+	if err := creds.Apply(req); err != nil {
+		return "", err
+	}
+	return req.Header.Get("Authorization"), nil
+}
+
+func TestClientCredentialsSingleFlight(t *testing.T) {
+	srv := newTokenServer(t, 3600, make(chan struct{}))
+	creds := ClientCredentials(ClientCredentialsConfig{TokenURL: srv.URL, ClientID: "id", ClientSecret: "secret"})
+
+	// a caller with a short deadline gives up without breaking the shared fetch
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := applyToken(ctx, creds); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want deadline exceeded, got %v", err)
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	tokens := make(chan string, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := applyToken(context.Background(), creds)
+			if err != nil {
+				t.Error(err)
+			}
+			tokens <- token
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(srv.release)
+	wg.Wait()
+	close(tokens)
+
+	for token := range tokens {
+		if token != "Bearer token-1" {
+			t.Fatalf("got %q, want the token of the first fetch", token)
+		}
+	}
+	if n := srv.hits.Load(); n != 1 {
+		t.Fatalf("%d token requests, want 1", n)
+	}
+}
+
+func TestClientCredentialsShortTTL(t *testing.T) {
+	// expires_in below tokenRefreshSkew still caches the token for half of its lifetime
+	srv := newTokenServer(t, 10, nil)
+	creds := ClientCredentials(ClientCredentialsConfig{TokenURL: srv.URL})
+	for i := 0; i < 3; i++ {
+		if _, err := applyToken(context.Background(), creds); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := srv.hits.Load(); n != 1 {
+		t.Fatalf("%d token requests, want 1", n)
+	}
+}
+
+func TestRefreshTime(t *testing.T) {
+	issued := time.Now()
+	for ttl, want := range map[time.Duration]time.Duration{
+		time.Hour:        time.Hour - tokenRefreshSkew,
+		time.Minute:      30 * time.Second,
+		10 * time.Second: 5 * time.Second,
+	} {
+		if got := refreshTime(issued, ttl).Sub(issued); got != want {
+			t.Errorf("ttl %s: refresh after %s, want %s", ttl, got, want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// BodyEncoder - тело запроса в формате, отличном от JSON.
+// Если RequestParams.RequestBody реализует BodyEncoder, тело не кодируется в JSON.
+type BodyEncoder interface {
+	Encode() (body io.Reader, contentType string, err error)
+}
+
+// Decoder - разбор тела успешного ответа в RequestParams.ResponseBody.
+type Decoder interface {
+	ContentType() string // отправляется в Accept
+	Decode(r io.Reader, v interface{}) error
+}
+
+var (
+	DecodeJSON    Decoder = jsonDecoder{}
+	DecodeXML     Decoder = xmlDecoder{}
+	DecodeMsgPack Decoder = msgpackDecoder{}
+)
+
+type jsonDecoder struct{}
+
+func (jsonDecoder) ContentType() string { return "application/json" }
+
+func (jsonDecoder) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+type xmlDecoder struct{}
+
+func (xmlDecoder) ContentType() string { return "application/xml" }
+
+func (xmlDecoder) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+type msgpackDecoder struct{}
+
+func (msgpackDecoder) ContentType() string { return "application/msgpack" }
+
+// Decode ожидает тип, сгенерированный msgp (реализующий msgp.Decodable).
+func (msgpackDecoder) Decode(r io.Reader, v interface{}) error {
+	d, ok := v.(msgp.Decodable)
+	if !ok {
+		return fmt.Errorf("client: %T does not implement msgp.Decodable", v)
+	}
+	return d.DecodeMsg(msgp.NewReader(r))
+}
+
+type rawBody struct {
+	body        io.Reader
+	contentType string
+}
+
+func (b *rawBody) Encode() (io.Reader, string, error) {
+	return b.body, b.contentType, nil
+}
+
+// Raw - тело запроса как есть. *bytes.Reader, *bytes.Buffer и *strings.Reader
+// можно отправить повторно, остальные потоки запрещают повтор запроса.
+func Raw(body io.Reader, contentType string) BodyEncoder {
+	return &rawBody{body: body, contentType: contentType}
+}
+
+// Form - тело application/x-www-form-urlencoded.
+func Form(values url.Values) BodyEncoder {
+	return &rawBody{
+		body:        strings.NewReader(values.Encode()),
+		contentType: "application/x-www-form-urlencoded",
+	}
+}
+
+type multipartPart struct {
+	field    string
+	filename string
+	header   textproto.MIMEHeader
+	value    string
+	body     io.Reader
+}
+
+// Multipart - тело multipart/form-data. Файлы не читаются в память, а передаются потоком,
+// поэтому такой запрос не повторяется политикой повторов.
+type Multipart struct {
+	parts []multipartPart
+}
+
+// NewMultipart создаёт пустое тело multipart/form-data.
+func NewMultipart() *Multipart {
+	return &Multipart{}
+}
+
+// Field добавляет текстовое поле формы.
+func (m *Multipart) Field(name, value string) *Multipart {
+	m.parts = append(m.parts, multipartPart{field: name, value: value})
+	return m
+}
+
+// File добавляет файл. Если body реализует io.Closer, он закрывается после отправки.
+func (m *Multipart) File(field, filename string, body io.Reader) *Multipart {
+	m.parts = append(m.parts, multipartPart{field: field, filename: filename, body: body})
+	return m
+}
+
+// Part добавляет часть с произвольными заголовками.
+func (m *Multipart) Part(header textproto.MIMEHeader, body io.Reader) *Multipart {
+	m.parts = append(m.parts, multipartPart{header: header, body: body})
+	return m
+}
+
+func (m *Multipart) Encode() (io.Reader, string, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	go func() {
+		err := m.write(mw)
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr, mw.FormDataContentType(), nil
+}
+
+func (m *Multipart) write(mw *multipart.Writer) error {
+	for i, p := range m.parts {
+		if err := p.write(mw); err != nil {
+			// оставшиеся файлы уже не будут отправлены, но их нужно закрыть
+			for _, rest := range m.parts[i+1:] {
+				rest.close()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (p multipartPart) write(mw *multipart.Writer) error {
+	defer p.close()
+
+	var (
+		w   io.Writer
+		err error
+	)
+	switch {
+	case p.header != nil:
+		w, err = mw.CreatePart(p.header)
+	case p.body != nil:
+		w, err = mw.CreateFormFile(p.field, p.filename)
+	default:
+		return mw.WriteField(p.field, p.value)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, p.body)
+	return err
+}
+
+func (p multipartPart) close() {
+	if c, ok := p.body.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// encodeBody готовит тело запроса: BodyEncoder или JSON.
+func encodeBody(requestBody interface{}) (io.Reader, string, error) {
+	if requestBody == nil {
+		return http.NoBody, "", nil
+	}
+	if enc, ok := requestBody.(BodyEncoder); ok {
+		return enc.Encode()
+	}
+	data, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, "", err
+	}
+	return bytes.NewReader(data), "application/json", nil
+}
+
+// Stream выполняет запрос и возвращает тело успешного ответа без чтения, его нужно закрыть.
+// Ответы 4xx/5xx разбираются как обычно.
+func (c *Client) Stream(ctx context.Context, method string, path string, params *RequestParams) (io.ReadCloser, error) {
+	p := RequestParams{}
+	if params != nil {
+		p = *params
+	}
+	p.ResponseBody = nil
+
+	resp, err := c.Do(ctx, method, path, &p)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
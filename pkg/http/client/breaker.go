@@ -0,0 +1,276 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	customErrors "github.com/mlplabs/common-go-pkg/pkg/http/errors/custom"
+)
+
+const (
+	breakerBuckets = 10
+
+	defaultBreakerWindow       = 10 * time.Second
+	defaultBreakerMinRequests  = 10
+	defaultBreakerFailureRatio = 0.5
+	defaultBreakerOpenTimeout  = 5 * time.Second
+)
+
+// ErrCircuitOpen - запрос отклонён без обращения к сервису, т.к. цепь разомкнута.
+// Клиент возвращает её обёрнутой в ServiceUnavailable (503).
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState - состояние цепи.
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Outcome - как учитывать результат запроса.
+type Outcome int
+
+const (
+	OutcomeDefault Outcome = iota
+	OutcomeSuccess
+	OutcomeFailure
+	OutcomeIgnore
+)
+
+// BreakerSettings - настройки circuit breaker.
+type BreakerSettings struct {
+	Window              time.Duration // скользящее окно подсчёта ошибок
+	MinRequests         int           // меньше запросов в окне - цепь не размыкается
+	FailureRatio        float64       // доля ошибок, при которой цепь размыкается
+	OpenTimeout         time.Duration // через сколько пробовать half-open
+	HalfOpenMaxRequests int           // пробных запросов в half-open
+
+	ServerErrors Outcome // 5xx, по умолчанию ошибка
+	Timeouts     Outcome // таймауты и сетевые ошибки, по умолчанию ошибка
+	ClientErrors Outcome // 4xx, по умолчанию не учитываются
+
+	OnStateChange func(service string, from, to BreakerState) // вызывается асинхронно
+}
+
+type breakerBucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+// CircuitBreaker - circuit breaker одного сервиса. Может быть общим для нескольких клиентов.
+type CircuitBreaker struct {
+	service  string
+	settings BreakerSettings
+
+	mu       sync.Mutex
+	state    BreakerState
+	openedAt time.Time
+	buckets  [breakerBuckets]breakerBucket
+	trials   int // запущено пробных запросов в half-open
+	passed   int // успешных пробных запросов
+}
+
+// NewCircuitBreaker создаёт circuit breaker для сервиса service.
+func NewCircuitBreaker(service string, settings BreakerSettings) *CircuitBreaker {
+	if settings.Window <= 0 {
+		settings.Window = defaultBreakerWindow
+	}
+	// окно делится на breakerBuckets корзин, корзина не может быть короче наносекунды
+	if settings.Window < breakerBuckets {
+		settings.Window = breakerBuckets
+	}
+	if settings.MinRequests <= 0 {
+		settings.MinRequests = defaultBreakerMinRequests
+	}
+	if settings.FailureRatio <= 0 {
+		settings.FailureRatio = defaultBreakerFailureRatio
+	}
+	if settings.OpenTimeout <= 0 {
+		settings.OpenTimeout = defaultBreakerOpenTimeout
+	}
+	if settings.HalfOpenMaxRequests <= 0 {
+		settings.HalfOpenMaxRequests = 1
+	}
+	if settings.ServerErrors == OutcomeDefault {
+		settings.ServerErrors = OutcomeFailure
+	}
+	if settings.Timeouts == OutcomeDefault {
+		settings.Timeouts = OutcomeFailure
+	}
+	if settings.ClientErrors == OutcomeDefault {
+		settings.ClientErrors = OutcomeIgnore
+	}
+	return &CircuitBreaker{service: service, settings: settings}
+}
+
+// WithCircuitBreaker - circuit breaker для запросов клиента.
+func WithCircuitBreaker(cb *CircuitBreaker) Option {
+	return func(c *Client) {
+		c.breaker = cb
+	}
+}
+
+// State - текущее состояние цепи.
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.tick(time.Now())
+	return cb.state
+}
+
+// allow резервирует запрос. done нужно вызвать с результатом запроса.
+func (cb *CircuitBreaker) allow() (done func(resp *http.Response, err error), err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	now := time.Now()
+	cb.tick(now)
+	switch cb.state {
+	case StateOpen:
+		return nil, ErrCircuitOpen
+	case StateHalfOpen:
+		if cb.trials >= cb.settings.HalfOpenMaxRequests {
+			return nil, ErrCircuitOpen
+		}
+		cb.trials++
+	}
+
+	state := cb.state
+	return func(resp *http.Response, err error) {
+		cb.record(state, cb.classify(resp, err))
+	}, nil
+}
+
+func (cb *CircuitBreaker) classify(resp *http.Response, err error) Outcome {
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return OutcomeIgnore
+		}
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+			return cb.settings.Timeouts
+		}
+		return OutcomeFailure
+	}
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return cb.settings.ServerErrors
+	case resp.StatusCode >= http.StatusBadRequest:
+		return cb.settings.ClientErrors
+	}
+	return OutcomeSuccess
+}
+
+func (cb *CircuitBreaker) record(state BreakerState, outcome Outcome) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	now := time.Now()
+	cb.tick(now)
+	if cb.state != state {
+		// результат запроса из предыдущего состояния цепи
+		return
+	}
+
+	switch cb.state {
+	case StateHalfOpen:
+		switch outcome {
+		case OutcomeFailure:
+			cb.setState(StateOpen, now)
+		case OutcomeSuccess:
+			cb.passed++
+			if cb.passed >= cb.settings.HalfOpenMaxRequests {
+				cb.setState(StateClosed, now)
+			}
+		default:
+			cb.trials--
+		}
+	case StateClosed:
+		b := cb.bucket(now)
+		switch outcome {
+		case OutcomeFailure:
+			b.failures++
+		case OutcomeSuccess:
+			b.successes++
+		default:
+			return
+		}
+
+		var total, failures int
+		for _, b := range cb.buckets {
+			if now.Sub(b.start) < cb.settings.Window {
+				total += b.successes + b.failures
+				failures += b.failures
+			}
+		}
+		if total >= cb.settings.MinRequests && float64(failures)/float64(total) >= cb.settings.FailureRatio {
+			cb.setState(StateOpen, now)
+		}
+	}
+}
+
+// tick переводит разомкнутую цепь в half-open по истечении OpenTimeout.
+func (cb *CircuitBreaker) tick(now time.Time) {
+	if cb.state == StateOpen && now.Sub(cb.openedAt) >= cb.settings.OpenTimeout {
+		cb.setState(StateHalfOpen, now)
+	}
+}
+
+func (cb *CircuitBreaker) bucket(now time.Time) *breakerBucket {
+	size := cb.settings.Window / breakerBuckets
+	start := now.Truncate(size)
+	b := &cb.buckets[(start.UnixNano()/int64(size))%breakerBuckets]
+	if !b.start.Equal(start) {
+		*b = breakerBucket{start: start}
+	}
+	return b
+}
+
+func (cb *CircuitBreaker) setState(state BreakerState, now time.Time) {
+	from := cb.state
+	cb.state = state
+	cb.trials, cb.passed = 0, 0
+	switch state {
+	case StateOpen:
+		cb.openedAt = now
+	case StateClosed:
+		cb.buckets = [breakerBuckets]breakerBucket{}
+	}
+	if cb.settings.OnStateChange != nil && from != state {
+		go cb.settings.OnStateChange(cb.service, from, state)
+	}
+}
+
+// doWithBreaker выполняет одну попытку запроса через circuit breaker.
+func (c *Client) doWithBreaker(req *http.Request) (*http.Response, error) {
+	if c.breaker == nil {
+		return c.sendTo(req)
+	}
+	done, err := c.breaker.allow()
+	if err != nil {
+		return nil, customErrors.NewServiceUnavailable(err, c.ownerServiceName)
+	}
+	resp, err := c.sendTo(req)
+	done(resp, err)
+	return resp, err
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mlplabs/common-go-pkg/pkg/http/errors/custom"
+)
+
+func TestBreakerStateMachine(t *testing.T) {
+	srv := newCountingServer(t)
+	srv.fail.Store(true)
+	changes := make(chan BreakerState, 10)
+	cb := NewCircuitBreaker("svc", BreakerSettings{
+		Window:        time.Minute,
+		MinRequests:   2,
+		FailureRatio:  0.5,
+		OpenTimeout:   50 * time.Millisecond,
+		OnStateChange: func(_ string, _, to BreakerState) { changes <- to },
+	})
+	c := NewClient("test", "svc", srv.URL, WithCircuitBreaker(cb))
+	expect := func(want BreakerState) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Fatalf("state changed to %s, want %s", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("state did not change to %s", want)
+		}
+	}
+
+	// closed -> open after MinRequests failures
+	c.Get(context.Background(), "/", nil)
+	if cb.State() != StateClosed {
+		t.Fatalf("opened before MinRequests: %s", cb.State())
+	}
+	c.Get(context.Background(), "/", nil)
+	expect(StateOpen)
+
+	// the open breaker does not call the service
+	_, err := c.Get(context.Background(), "/", nil)
+	var unavailable *custom.ServiceUnavailable
+	if !errors.As(err, &unavailable) || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("want 503 with ErrCircuitOpen, got %v", err)
+	}
+	if srv.hits.Load() != 2 {
+		t.Fatalf("%d requests reached the service, want 2", srv.hits.Load())
+	}
+
+	// open -> half-open after OpenTimeout, a failed trial opens it again
+	time.Sleep(60 * time.Millisecond)
+	if cb.State() != StateHalfOpen {
+		t.Fatalf("state %s, want half-open", cb.State())
+	}
+	expect(StateHalfOpen)
+	c.Get(context.Background(), "/", nil)
+	expect(StateOpen)
+
+	// a successful trial closes it
+	srv.fail.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	resp, err := c.Get(context.Background(), "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	expect(StateHalfOpen)
+	expect(StateClosed)
+	if srv.hits.Load() != 4 {
+		t.Fatalf("%d requests reached the service, want 4", srv.hits.Load())
+	}
+}
+
+func TestBreakerHalfOpenLimitsTrials(t *testing.T) {
+	cb := NewCircuitBreaker("svc", BreakerSettings{MinRequests: 1, OpenTimeout: time.Millisecond})
+	done, err := cb.allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done(nil, errors.New("connection refused"))
+	time.Sleep(2 * time.Millisecond)
+
+	trial, err := cb.allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cb.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second trial in half-open: %v", err)
+	}
+	// an ignored outcome frees the trial
+	trial(nil, context.Canceled)
+	if _, err := cb.allow(); err != nil {
+		t.Fatalf("trial was not freed: %v", err)
+	}
+}
+
+func TestBreakerIgnoresClientErrors(t *testing.T) {
+	cb := NewCircuitBreaker("svc", BreakerSettings{MinRequests: 1})
+	for i := 0; i < 5; i++ {
+		done, err := cb.allow()
+		if err != nil {
+			t.Fatal(err)
+		}
+		done(&http.Response{StatusCode: http.StatusNotFound}, nil)
+	}
+	if cb.State() != StateClosed {
+		t.Fatalf("4xx opened the breaker: %s", cb.State())
+	}
+}
+
+func TestBreakerShortWindow(t *testing.T) {
+	cb := NewCircuitBreaker("svc", BreakerSettings{Window: 5 * time.Nanosecond, MinRequests: 100})
+	for i := 0; i < 5; i++ {
+		done, err := cb.allow()
+		if err != nil {
+			t.Fatal(err)
+		}
+		done(&http.Response{StatusCode: http.StatusOK}, nil)
+	}
+}
@@ -0,0 +1,410 @@
+package client
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultCacheKeepStale = 10 * time.Minute
+
+// CachedResponse - сохранённый ответ.
+type CachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	StoredAt   time.Time   `json:"stored_at"`
+	Expires    time.Time   `json:"expires"` // до этого момента ответ отдаётся без обращения к сервису
+	// Vary - значения заголовков запроса, перечисленных в Vary ответа.
+	Vary map[string]string `json:"vary,omitempty"`
+}
+
+func (e *CachedResponse) fresh(now time.Time) bool {
+	return now.Before(e.Expires)
+}
+
+// matches - подходит ли ответ запросу по заголовкам из Vary.
+func (e *CachedResponse) matches(req *http.Request) bool {
+	for name, value := range e.Vary {
+		if req.Header.Get(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *CachedResponse) response(req *http.Request) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(time.Since(e.StoredAt).Seconds())))
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// CacheStore - хранилище ответов.
+type CacheStore interface {
+	Get(ctx context.Context, key string) (*CachedResponse, bool, error)
+	Set(ctx context.Context, key string, entry *CachedResponse, ttl time.Duration) error
+}
+
+// CacheOptions - настройки кеша ответов.
+type CacheOptions struct {
+	// KeepStale - сколько хранить устаревший ответ с ETag/Last-Modified для перепроверки.
+	KeepStale time.Duration
+}
+
+// Cache - HTTP-кеш GET-запросов клиента с учётом Cache-Control, Expires, ETag и Last-Modified.
+// Устаревшие ответы перепроверяются условными запросами, одинаковые одновременные запросы
+// объединяются в один. Ответы на запросы с разными Authorization хранятся раздельно,
+// ответ с Vary отдаётся только запросам с теми же значениями перечисленных в нём заголовков.
+//
+//	client.NewClient(name, owner, url, client.WithMiddleware(client.Cache(client.NewMemoryCache(1000), client.CacheOptions{})))
+func Cache(store CacheStore, opts CacheOptions) Middleware {
+	if opts.KeepStale <= 0 {
+		opts.KeepStale = defaultCacheKeepStale
+	}
+	group := &flightGroup{calls: make(map[string]*flightCall)}
+
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodGet || hasDirective(req.Header, "no-store") {
+				return next.Do(req)
+			}
+
+			ctx := req.Context()
+			key := cacheKey(req)
+			cached, ok, err := store.Get(ctx, key)
+			if err != nil || !ok || !cached.matches(req) {
+				cached = nil
+			}
+			if cached != nil && cached.fresh(time.Now()) && !hasDirective(req.Header, "no-cache") {
+				return cached.response(req), nil
+			}
+
+			entry, err := group.do(ctx, key, func() (*CachedResponse, error) {
+				return fetchAndStore(req, next, store, key, cached, opts)
+			})
+			if err != nil {
+				return nil, err
+			}
+			if !entry.matches(req) {
+				// общий запрос выполнен с другими значениями заголовков из Vary
+				entry, err = group.do(ctx, key+varyKey(req, entry.Vary), func() (*CachedResponse, error) {
+					return fetchAndStore(req, next, store, key, nil, opts)
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+			return entry.response(req), nil
+		})
+	}
+}
+
+func fetchAndStore(req *http.Request, next Doer, store CacheStore, key string, cached *CachedResponse, opts CacheOptions) (*CachedResponse, error) {
+	ctx := req.Context()
+	outReq := req
+	if cached != nil {
+		outReq = req.Clone(ctx)
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			outReq.Header.Set("If-None-Match", etag)
+		}
+		if lm := cached.Header.Get("Last-Modified"); lm != "" {
+			outReq.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	resp, err := next.Do(outReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	now := time.Now()
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		for _, h := range []string{"Cache-Control", "Expires", "ETag", "Last-Modified", "Date"} {
+			if v := resp.Header.Get(h); v != "" {
+				cached.Header.Set(h, v)
+			}
+		}
+		cached.StoredAt = now
+		cached.Expires = now.Add(freshness(cached.Header, now))
+		storeEntry(ctx, store, key, cached, opts)
+		return cached, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	entry := &CachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		StoredAt:   now,
+		Expires:    now.Add(freshness(resp.Header, now)),
+		Vary:       varyValues(req, resp.Header),
+	}
+	if cacheable(resp) {
+		storeEntry(ctx, store, key, entry, opts)
+	}
+	return entry, nil
+}
+
+func storeEntry(ctx context.Context, store CacheStore, key string, entry *CachedResponse, opts CacheOptions) {
+	ttl := time.Until(entry.Expires)
+	if entry.Header.Get("ETag") != "" || entry.Header.Get("Last-Modified") != "" {
+		ttl = max(ttl, 0) + opts.KeepStale
+	}
+	if ttl > 0 {
+		_ = store.Set(ctx, key, entry, ttl)
+	}
+}
+
+func cacheable(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK || hasDirective(resp.Header, "no-store") {
+		return false
+	}
+	return resp.Header.Get("Vary") != "*"
+}
+
+// varyValues - значения заголовков запроса, от которых по Vary зависит ответ.
+func varyValues(req *http.Request, h http.Header) map[string]string {
+	var values map[string]string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			if values == nil {
+				values = make(map[string]string)
+			}
+			values[http.CanonicalHeaderKey(name)] = req.Header.Get(name)
+		}
+	}
+	return values
+}
+
+// varyKey - значения заголовков запроса из vary для ключа объединения запросов.
+func varyKey(req *http.Request, vary map[string]string) string {
+	names := slices.Sorted(maps.Keys(vary))
+	var key strings.Builder
+	for _, name := range names {
+		key.WriteString(" " + name + "=" + req.Header.Get(name))
+	}
+	return key.String()
+}
+
+// freshness - время жизни ответа по max-age или Expires.
+func freshness(h http.Header, now time.Time) time.Duration {
+	if hasDirective(h, "no-cache") {
+		return 0
+	}
+	for _, directive := range strings.Split(h.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "max-age") {
+			if sec, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				return time.Duration(sec) * time.Second
+			}
+		}
+	}
+	if expires, err := http.ParseTime(h.Get("Expires")); err == nil {
+		return max(expires.Sub(now), 0)
+	}
+	return 0
+}
+
+func hasDirective(h http.Header, directive string) bool {
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(d), "=")
+			if strings.EqualFold(name, directive) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func cacheKey(req *http.Request) string {
+	key := req.Method + " " + req.URL.String()
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		sum := sha256.Sum256([]byte(auth))
+		key += " " + hex.EncodeToString(sum[:8])
+	}
+	if accept := req.Header.Get("Accept"); accept != "" {
+		key += " " + accept
+	}
+	return key
+}
+
+type flightCall struct {
+	done  chan struct{}
+	entry *CachedResponse
+	err   error
+}
+
+// flightGroup объединяет одновременные одинаковые запросы.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do выполняет fn один раз для одновременных вызовов с ключом key. Ожидающие вызовы
+// прерываются своим ctx. Отмена контекста ведущего запроса не передаётся остальным:
+// один из них выполняет запрос заново.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() (*CachedResponse, error)) (*CachedResponse, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		g.mu.Lock()
+		if call, ok := g.calls[key]; ok {
+			g.mu.Unlock()
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded) {
+				continue
+			}
+			return call.entry, call.err
+		}
+		call := &flightCall{done: make(chan struct{})}
+		g.calls[key] = call
+		g.mu.Unlock()
+
+		call.entry, call.err = fn()
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+		return call.entry, call.err
+	}
+}
+
+// MemoryCache - LRU-кеш ответов в памяти.
+type MemoryCache struct {
+	mu      sync.Mutex
+	size    int
+	items   map[string]*list.Element
+	evictor *list.List
+}
+
+type memoryCacheItem struct {
+	key     string
+	entry   *CachedResponse
+	expires time.Time
+}
+
+// NewMemoryCache создаёт LRU-кеш на size ответов.
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{
+		size:    max(size, 1),
+		items:   make(map[string]*list.Element),
+		evictor: list.New(),
+	}
+}
+
+func (m *MemoryCache) Get(_ context.Context, key string) (*CachedResponse, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	item := el.Value.(*memoryCacheItem)
+	if time.Now().After(item.expires) {
+		m.evictor.Remove(el)
+		delete(m.items, key)
+		return nil, false, nil
+	}
+	m.evictor.MoveToFront(el)
+	entry := *item.entry
+	entry.Header = item.entry.Header.Clone()
+	return &entry, true, nil
+}
+
+func (m *MemoryCache) Set(_ context.Context, key string, entry *CachedResponse, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item := &memoryCacheItem{key: key, entry: entry, expires: time.Now().Add(ttl)}
+	if el, ok := m.items[key]; ok {
+		el.Value = item
+		m.evictor.MoveToFront(el)
+		return nil
+	}
+	m.items[key] = m.evictor.PushFront(item)
+	if m.evictor.Len() > m.size {
+		oldest := m.evictor.Back()
+		m.evictor.Remove(oldest)
+		delete(m.items, oldest.Value.(*memoryCacheItem).key)
+	}
+	return nil
+}
+
+// RedisCache - кеш ответов в Redis, клиент создаётся redis.NewRedisClient.
+type RedisCache struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisCache создаёт кеш в Redis, ключи начинаются с prefix.
+func NewRedisCache(client *redis.Client, prefix string) *RedisCache {
+	return &RedisCache{client: client, prefix: prefix}
+}
+
+func (r *RedisCache) Get(ctx context.Context, key string) (*CachedResponse, bool, error) {
+	data, err := r.client.Get(ctx, r.key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	entry := &CachedResponse{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, false, err
+	}
+	return entry, true, nil
+}
+
+func (r *RedisCache) Set(ctx context.Context, key string, entry *CachedResponse, ttl time.Duration) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.key(key), data, ttl).Err()
+}
+
+func (r *RedisCache) key(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return r.prefix + hex.EncodeToString(sum[:])
+}
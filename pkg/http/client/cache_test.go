@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// cacheServer answers with the X-Tenant of the request, cacheable for a minute and varying by X-Tenant.
+// Requests with If-None-Match "v1" get 304.
+type cacheServer struct {
+	*httptest.Server
+	hits        atomic.Int64
+	revalidated atomic.Int64
+	delay       time.Duration
+	maxAge      string
+}
+
+func newCacheServer(t *testing.T, delay time.Duration, maxAge string) *cacheServer {
+	s := &cacheServer{delay: delay, maxAge: maxAge}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		time.Sleep(s.delay)
+		w.Header().Set("Cache-Control", "max-age="+s.maxAge)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Vary", "X-Tenant")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			s.revalidated.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		json.NewEncoder(w).Encode(r.Header.Get("X-Tenant"))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func getTenant(t *testing.T, c *Client, tenant string) string {
+	t.Helper()
+	var got string
+	_, err := c.Get(context.Background(), "/", &RequestParams{
+		ResponseBody: &got,
+		RequestHandler: func(r *http.Request) *http.Request {
+			r.Header.Set("X-Tenant", tenant)
+			return r
+		},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	return got
+}
+
+func TestCacheVary(t *testing.T) {
+	srv := newCacheServer(t, 0, "60")
+	c := NewClient("test", "svc", srv.URL, WithMiddleware(Cache(NewMemoryCache(10), CacheOptions{})))
+
+	if got := getTenant(t, c, "a"); got != "a" {
+		t.Fatalf("tenant a got %q", got)
+	}
+	if got := getTenant(t, c, "a"); got != "a" || srv.hits.Load() != 1 {
+		t.Fatalf("tenant a got %q, %d requests, want a cached response", got, srv.hits.Load())
+	}
+	if got := getTenant(t, c, "b"); got != "b" {
+		t.Fatalf("tenant b got the response of %q", got)
+	}
+	if srv.hits.Load() != 2 {
+		t.Fatalf("%d requests, want 2", srv.hits.Load())
+	}
+}
+
+func TestCacheFlightGroupVary(t *testing.T) {
+	srv := newCacheServer(t, 100*time.Millisecond, "60")
+	c := NewClient("test", "svc", srv.URL, WithMiddleware(Cache(NewMemoryCache(10), CacheOptions{})))
+
+	// concurrent requests of one tenant are merged, another tenant does not get their response
+	var wg sync.WaitGroup
+	for _, tenant := range []string{"a", "a", "a", "b", "b"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := getTenant(t, c, tenant); got != tenant {
+				t.Errorf("tenant %s got the response of %q", tenant, got)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := srv.hits.Load(); n != 2 {
+		t.Fatalf("%d requests, want one per tenant", n)
+	}
+}
+
+func TestCacheRevalidates(t *testing.T) {
+	srv := newCacheServer(t, 0, "0")
+	c := NewClient("test", "svc", srv.URL, WithMiddleware(Cache(NewMemoryCache(10), CacheOptions{})))
+
+	for i := 0; i < 3; i++ {
+		if got := getTenant(t, c, "a"); got != "a" {
+			t.Fatalf("request %d got %q", i, got)
+		}
+	}
+	if srv.hits.Load() != 3 || srv.revalidated.Load() != 2 {
+		t.Fatalf("%d requests, %d revalidated, want 3 and 2", srv.hits.Load(), srv.revalidated.Load())
+	}
+}
@@ -1,7 +1,6 @@
 package client
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -9,187 +8,305 @@ import (
 	httpErrors "github.com/mlplabs/common-go-pkg/pkg/http/errors"
 	customErrors "github.com/mlplabs/common-go-pkg/pkg/http/errors/custom"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
+// Token - строковый ключ токена в контексте.
+//
+// Deprecated: ключ может совпасть с ключами других пакетов, используйте ContextWithToken.
 const Token string = "TOKEN"
 
+// RequestParams - параметры запроса.
+// RequestBody кодируется в JSON, если не реализует BodyEncoder (Form, Raw, Multipart).
 type RequestParams struct {
 	ProxyToken     bool
 	RequestBody    interface{}
 	ResponseBody   interface{}
 	RequestHandler func(request *http.Request) *http.Request
+	IdempotencyKey string        // разрешает повтор неидемпотентного запроса
+	OnAttempt      func(Attempt) // вызывается после каждой попытки
+	Timeout        time.Duration // таймаут запроса, включая чтение тела ответа
+	PathParams     map[string]string
+	Query          interface{} // url.Values, map или структура с тегами `url:"name,omitempty"`
+	// ResponseDecoder разбирает ответ не в JSON, например DecodeXML.
+	ResponseDecoder Decoder
+	// Credentials - авторизация запроса вместо заданной WithCredentials.
+	Credentials Credentials
 }
 
+// Client - HTTP-клиент сервиса. Безопасен для одновременного использования из нескольких горутин.
 type Client struct {
 	client           *http.Client
 	clientName       string // какой сервис выполняет запрос
 	ownerServiceName string // какому сервису запрос
 	baseURL          string
-	body             []byte
+	retry            *RetryPolicy
+	breaker          *CircuitBreaker
+	limiter          *limiter
+	hedge            *HedgePolicy
+	discovery        *Discovery
+	headers          http.Header // заголовки каждого запроса
+	credentials      Credentials
+	middleware       []Middleware
+	doer             Doer
+
+	// транспорт настраивается опциями до создания клиента
+	dialer       *net.Dialer
+	transport    *http.Transport
+	roundTripper http.RoundTripper
 }
 
-func NewClient(clientName string, ownerServiceName string, baseURL string) *Client {
-	return &Client{
+func NewClient(clientName string, ownerServiceName string, baseURL string, opts ...Option) *Client {
+	dialer := &net.Dialer{
+		Timeout:   defaultDialTimeout,
+		KeepAlive: defaultKeepAlive,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+
+	c := &Client{
 		client:           &http.Client{},
 		clientName:       clientName,
 		ownerServiceName: ownerServiceName,
 		baseURL:          baseURL,
+		headers:          http.Header{},
+		dialer:           dialer,
+		transport:        transport,
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	c.client.Transport = c.transport
+	if c.roundTripper != nil {
+		c.client.Transport = c.roundTripper
+	}
+	c.doer = chain(c.middleware, DoerFunc(func(req *http.Request) (*http.Response, error) {
+		return c.doWithRetry(req, attemptHook(req.Context()))
+	}))
+
+	return c
 }
 
 func (c *Client) GetBaseURL() string {
 	return c.baseURL
 }
 func (c *Client) Get(ctx context.Context, url string, params *RequestParams) (*http.Response, error) {
-	return c.get(ctx, http.MethodGet, url, params)
+	return c.Do(ctx, http.MethodGet, url, params)
+}
+func (c *Client) Head(ctx context.Context, url string, params *RequestParams) (*http.Response, error) {
+	return c.Do(ctx, http.MethodHead, url, params)
+}
+func (c *Client) Options(ctx context.Context, url string, params *RequestParams) (*http.Response, error) {
+	return c.Do(ctx, http.MethodOptions, url, params)
 }
 func (c *Client) Delete(ctx context.Context, url string, params *RequestParams) (*http.Response, error) {
-	return c.get(ctx, http.MethodDelete, url, params)
+	return c.Do(ctx, http.MethodDelete, url, params)
 }
 func (c *Client) Post(ctx context.Context, url string, params *RequestParams) (*http.Response, error) {
-	return c.post(ctx, http.MethodPost, url, params)
+	return c.Do(ctx, http.MethodPost, url, params)
 }
 func (c *Client) Put(ctx context.Context, url string, params *RequestParams) (*http.Response, error) {
-	return c.post(ctx, http.MethodPut, url, params)
+	return c.Do(ctx, http.MethodPut, url, params)
+}
+func (c *Client) Patch(ctx context.Context, url string, params *RequestParams) (*http.Response, error) {
+	return c.Do(ctx, http.MethodPatch, url, params)
 }
 
-func (c *Client) get(ctx context.Context, method string, url string, params *RequestParams) (*http.Response, error) {
-	bodyBuffer := bytes.NewBuffer([]byte(""))
-	if params != nil && params.RequestBody != nil {
-		body, err := json.Marshal(params.RequestBody)
-		if err != nil {
-			return nil, err
-		}
-		bodyBuffer = bytes.NewBuffer(body)
+// Do выполняет запрос method к path относительно baseURL.
+// path может содержать шаблоны {name}, значения берутся из params.PathParams и экранируются.
+// При коде ответа 4xx/5xx возвращается ошибка из стандартного ответа сервиса.
+func (c *Client) Do(ctx context.Context, method string, path string, params *RequestParams) (*http.Response, error) {
+	if params == nil {
+		params = &RequestParams{}
 	}
-	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/%s", c.GetBaseURL(), url), bodyBuffer)
-	if err != nil {
-		return nil, err
+	if params.Timeout <= 0 {
+		return c.do(ctx, method, path, params)
 	}
 
-	if c.clientName != "" {
-		req.Header.Add("X-Service-Name", c.clientName)
+	// если тело уже прочитано в do, контекст отменяется сразу, иначе при закрытии тела ответа
+	ctx, cancel := context.WithTimeout(ctx, params.Timeout)
+	resp, err := c.do(ctx, method, path, params)
+	if resp == nil || resp.Body == nil || err != nil || params.ResponseBody != nil {
+		cancel()
+		return resp, err
 	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, err
+}
 
-	if params != nil {
-		if params.ProxyToken {
-			token, ok := c.GetToken(ctx)
-			if !ok {
-				return nil, fmt.Errorf("no token in context")
-			}
-			req.Header.Add("Authorization", "Bearer "+token)
-		}
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
 
-		if params.RequestHandler != nil {
-			req = params.RequestHandler(req)
-		}
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, params *RequestParams) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, path, params)
+	if err != nil {
+		return nil, err
 	}
 
-	resp, err := c.client.Do(req)
+	resp, err := c.doer.Do(req)
 	if err != nil {
+		// тело могло не дойти до транспорта, например при открытом автомате или отказе лимитера;
+		// закрытие останавливает запись потоковых тел, таких как Multipart
+		if req.Body != nil {
+			req.Body.Close()
+		}
 		return nil, err
 	}
 
 	if resp.StatusCode < http.StatusBadRequest {
-		if params != nil && params.ResponseBody != nil {
-			err = c.ReadBody(resp)
+		if params.ResponseBody != nil {
+			if params.ResponseDecoder != nil {
+				defer resp.Body.Close()
+				if err := params.ResponseDecoder.Decode(resp.Body, params.ResponseBody); err != nil {
+					return nil, err
+				}
+				return resp, nil
+			}
+			body, err := c.ReadBody(resp)
 			if err != nil {
 				return nil, err
 			}
-			err = json.Unmarshal(c.body, &params.ResponseBody)
+			err = json.Unmarshal(body, &params.ResponseBody)
 			if err != nil {
 				return nil, err
 			}
 		}
 		return resp, err
 	} else {
-		err = c.ReadBody(resp)
+		body, err := c.ReadBody(resp)
 		if err != nil {
 			return nil, err
 		}
-		return resp, c.ParseError(resp.StatusCode)
+		return resp, c.ParseError(resp.StatusCode, body)
 	}
 }
 
-func (c *Client) post(ctx context.Context, method string, url string, params *RequestParams) (*http.Response, error) {
-	bodyBuffer := bytes.NewBuffer([]byte(""))
-	if params != nil && params.RequestBody != nil {
-		body, err := json.Marshal(params.RequestBody)
-		if err != nil {
-			return nil, err
-		}
-		bodyBuffer = bytes.NewBuffer(body)
+func (c *Client) newRequest(ctx context.Context, method string, path string, params *RequestParams) (*http.Request, error) {
+	reqURL, err := c.buildURL(path, params)
+	if err != nil {
+		return nil, err
 	}
-	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/%s", c.GetBaseURL(), url), bodyBuffer)
+
+	body, contentType, err := encodeBody(params.RequestBody)
 	if err != nil {
 		return nil, err
 	}
+	reqCtx := withRoute(withAttemptHook(ctx, params.OnAttempt), path)
+	req, err := http.NewRequestWithContext(reqCtx, method, reqURL, body)
+	if err != nil {
+		if c, ok := body.(io.Closer); ok {
+			c.Close()
+		}
+		return nil, err
+	}
 
+	for k, values := range c.headers {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if params.ResponseDecoder != nil {
+		req.Header.Set("Accept", params.ResponseDecoder.ContentType())
+	}
 	if c.clientName != "" {
 		req.Header.Add("X-Service-Name", c.clientName)
 	}
 
-	if params != nil {
-		if params.ProxyToken {
-			token, ok := c.GetToken(ctx)
-			if !ok {
-				return nil, fmt.Errorf("no token in context")
-			}
-			req.Header.Add("Authorization", token)
+	creds := c.credentials
+	if params.ProxyToken {
+		creds = ForwardToken()
+	}
+	if params.Credentials != nil {
+		creds = params.Credentials
+	}
+	if creds != nil {
+		if err := creds.Apply(req); err != nil {
+			req.Body.Close()
+			return nil, err
 		}
+	}
 
-		if params.RequestHandler != nil {
-			req = params.RequestHandler(req)
-		}
+	if params.IdempotencyKey != "" {
+		req.Header.Set(idempotencyKeyHeader, params.IdempotencyKey)
 	}
 
-	resp, err := c.client.Do(req)
+	if params.RequestHandler != nil {
+		req = params.RequestHandler(req)
+	}
+	return req, nil
+}
+
+// buildURL склеивает baseURL и path, подставляет параметры пути и добавляет query.
+func (c *Client) buildURL(path string, params *RequestParams) (string, error) {
+	path, rawQuery, _ := strings.Cut(path, "?")
+	for name, value := range params.PathParams {
+		path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(value))
+	}
+	if strings.Contains(path, "{") {
+		return "", fmt.Errorf("client: unresolved path parameter in %q", path)
+	}
+
+	u, err := url.Parse(strings.TrimRight(c.GetBaseURL(), "/") + "/" + strings.TrimLeft(path, "/"))
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	if resp.StatusCode < http.StatusBadRequest {
-		if params != nil && params.ResponseBody != nil {
-			err = c.ReadBody(resp)
-			if err != nil {
-				return nil, err
-			}
-			err = json.Unmarshal(c.body, &params.ResponseBody)
-			if err != nil {
-				return nil, err
-			}
-		}
-		return resp, err
-	} else {
-		err = c.ReadBody(resp)
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", err
+	}
+	if params.Query != nil {
+		values, err := EncodeQuery(params.Query)
 		if err != nil {
-			return nil, err
+			return "", err
+		}
+		for k, v := range values {
+			query[k] = append(query[k], v...)
 		}
-		return resp, c.ParseError(resp.StatusCode)
 	}
-
+	u.RawQuery = query.Encode()
+	return u.String(), nil
 }
 
-func (c *Client) ReadBody(resp *http.Response) error {
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
+// bearer приводит токен к виду "Bearer <token>", токен в контексте может быть как с префиксом, так и без.
+func bearer(token string) string {
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = token[7:]
 	}
-	c.body = body
-	resp.Body.Close()
-	return nil
+	return "Bearer " + token
+}
+
+// ReadBody читает и закрывает тело ответа.
+func (c *Client) ReadBody(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
 }
 
 func (c *Client) GetToken(ctx context.Context) (string, bool) {
-	val := ctx.Value(Token)
-	value, ok := val.(string)
-	return value, ok
+	return TokenFromContext(ctx)
 }
 
-func (c *Client) ParseError(statusCode int) error {
+// ParseError разбирает стандартный ответ с ошибкой сервиса.
+func (c *Client) ParseError(statusCode int, body []byte) error {
 	r := httpErrors.ResponseError{}
-	err := json.Unmarshal(c.body, &r)
+	err := json.Unmarshal(body, &r)
 	if err != nil {
 		return fmt.Errorf("client error: can not unmarshal body from %s: %s", c.ownerServiceName, err)
 	}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/mlplabs/common-go-pkg/pkg/http/errors/custom"
+)
+
+type echoItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// newEchoServer answers GET /items/{id} with the item, POST /items with the posted item
+// and any request to /fail/{id} with a standard error envelope carrying the id.
+func newEchoServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		json.NewEncoder(w).Encode(echoItem{ID: id, Name: "item-" + r.PathValue("id")})
+	})
+	mux.HandleFunc("POST /items", func(w http.ResponseWriter, r *http.Request) {
+		var item echoItem
+		if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(item)
+	})
+	mux.HandleFunc("/fail/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, `{"error":{"code":"CONFLICT_%s","message":"conflict %s"}}`, r.PathValue("id"), r.PathValue("id"))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClientConcurrentCalls(t *testing.T) {
+	srv := newEchoServer(t)
+	c := NewClient("test", "echo", srv.URL)
+
+	const workers = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*3)
+	for i := 0; i < workers; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			var got echoItem
+			_, err := c.Get(context.Background(), "/items/"+strconv.Itoa(i), &RequestParams{ResponseBody: &got})
+			if err != nil {
+				errs <- fmt.Errorf("get %d: %w", i, err)
+				return
+			}
+			if got.ID != i || got.Name != "item-"+strconv.Itoa(i) {
+				errs <- fmt.Errorf("get %d: got %+v", i, got)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			want := echoItem{ID: i, Name: "posted-" + strconv.Itoa(i)}
+			var got echoItem
+			resp, err := c.Post(context.Background(), "/items", &RequestParams{RequestBody: want, ResponseBody: &got})
+			if err != nil {
+				errs <- fmt.Errorf("post %d: %w", i, err)
+				return
+			}
+			if resp.StatusCode != http.StatusCreated || got != want {
+				errs <- fmt.Errorf("post %d: status %d, got %+v", i, resp.StatusCode, got)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_, err := c.Do(context.Background(), http.MethodPut, "/fail/"+strconv.Itoa(i), &RequestParams{})
+			var common *custom.CommonError
+			if !errors.As(err, &common) {
+				errs <- fmt.Errorf("do %d: unexpected error %v", i, err)
+				return
+			}
+			if common.ErrorCode() != "CONFLICT_"+strconv.Itoa(i) || common.StatusCode() != http.StatusConflict {
+				errs <- fmt.Errorf("do %d: got %s %d", i, common.ErrorCode(), common.StatusCode())
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestClientConcurrentReadBody(t *testing.T) {
+	srv := newEchoServer(t)
+	c := NewClient("test", "echo", srv.URL)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := c.Get(context.Background(), "/items/"+strconv.Itoa(i), nil)
+			if err != nil {
+				t.Errorf("get %d: %v", i, err)
+				return
+			}
+			body, err := c.ReadBody(resp)
+			if err != nil {
+				t.Errorf("read %d: %v", i, err)
+				return
+			}
+			var got echoItem
+			if err := json.Unmarshal(body, &got); err != nil || got.ID != i {
+				t.Errorf("body %d: %s", i, body)
+			}
+			// ReadBody closes the body, reading it again fails with more than io.EOF
+			if _, err := resp.Body.Read(make([]byte, 1)); err == nil || err == io.EOF {
+				t.Errorf("body %d is still open", i)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
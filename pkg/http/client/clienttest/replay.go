@@ -0,0 +1,277 @@
+package clienttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// RecordEnv - переменная окружения, при RecordEnv=1 Recorder записывает взаимодействия заново.
+const RecordEnv = "CLIENTTEST_RECORD"
+
+// Mode - режим Recorder.
+type Mode int
+
+const (
+	// ModeReplay - ответы берутся из golden-файла, сеть не используется.
+	ModeReplay Mode = iota
+	// ModeRecord - запросы выполняются по-настоящему, взаимодействия сохраняются в golden-файл.
+	ModeRecord
+)
+
+// ModeFromEnv - ModeRecord при RecordEnv=1, иначе ModeReplay.
+func ModeFromEnv() Mode {
+	if record, _ := strconv.ParseBool(os.Getenv(RecordEnv)); record {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// redactedHeaders не сохраняются в golden-файл.
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// multipartBoundary заменяет случайную границу multipart-тела в golden-файле.
+const multipartBoundary = "clienttest-boundary"
+
+// BodyMatcher сравнивает тело сохранённого запроса с телом выполняемого.
+type BodyMatcher func(recorded, actual RecordedRequest) bool
+
+// ExactBody - тела совпадают побайтно. Граница multipart-тел заменяется постоянной, поэтому
+// такие запросы тоже сопоставляются. Используется по умолчанию.
+func ExactBody(recorded, actual RecordedRequest) bool {
+	return recorded.Body == actual.Body
+}
+
+// JSONBody - тела совпадают как JSON, без учёта порядка ключей и форматирования.
+func JSONBody(recorded, actual RecordedRequest) bool {
+	if recorded.Body == actual.Body {
+		return true
+	}
+	var a, b interface{}
+	if json.Unmarshal([]byte(recorded.Body), &a) != nil || json.Unmarshal([]byte(actual.Body), &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// AnyBody - тело не сравнивается.
+func AnyBody(RecordedRequest, RecordedRequest) bool {
+	return true
+}
+
+// Interaction - запрос и ответ на него.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest - сохранённый запрос. URL без схемы и хоста, чтобы не зависеть от адреса сервиса.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse - сохранённый ответ.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Recorder - транспорт, записывающий взаимодействия с сервисом в golden-файл
+// и воспроизводящий их без сети. При воспроизведении запрос сопоставляется
+// с первым неиспользованным взаимодействием с тем же методом, URL и телом, см. MatchBody.
+//
+//	rec := clienttest.NewRecorder(t, "testdata/items.json", clienttest.ModeFromEnv(), nil)
+//	c := client.NewClient("orders", "items", itemsURL, client.WithRoundTripper(rec))
+type Recorder struct {
+	t    testing.TB
+	path string
+	mode Mode
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+	matchBody    BodyMatcher
+}
+
+// NewRecorder создаёт Recorder для golden-файла path. next - транспорт для записи,
+// по умолчанию http.DefaultTransport. В режиме записи файл сохраняется при завершении теста.
+func NewRecorder(t testing.TB, path string, mode Mode, next http.RoundTripper) *Recorder {
+	t.Helper()
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{t: t, path: path, mode: mode, next: next, matchBody: ExactBody}
+
+	if mode == ModeRecord {
+		t.Cleanup(func() {
+			if err := r.Save(); err != nil {
+				t.Errorf("clienttest: %v", err)
+			}
+		})
+		return r
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("clienttest: read golden file (run with %s=1 to record): %v", RecordEnv, err)
+	}
+	if err := json.Unmarshal(data, &r.interactions); err != nil {
+		t.Fatalf("clienttest: parse golden file %s: %v", path, err)
+	}
+	r.used = make([]bool, len(r.interactions))
+	return r
+}
+
+// Interactions - записанные или загруженные взаимодействия.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// MatchBody задаёт сравнение тел при воспроизведении, по умолчанию ExactBody.
+func (r *Recorder) MatchBody(m BodyMatcher) *Recorder {
+	r.mu.Lock()
+	r.matchBody = m
+	r.mu.Unlock()
+	return r
+}
+
+// Save записывает взаимодействия в golden-файл.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTripper не должен изменять запрос, тело передаётся дальше в копии
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	if body != nil {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	header, recordedBody := normalizeMultipart(redact(req.Header), body)
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    req.URL.RequestURI(),
+		Header: header,
+		Body:   recordedBody,
+	}
+
+	if r.mode == ModeRecord {
+		return r.record(req, recorded)
+	}
+	return r.replay(req, recorded)
+}
+
+func (r *Recorder) record(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     redact(resp.Header),
+			Body:       string(respBody),
+		},
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, in := range r.interactions {
+		if r.used[i] || in.Request.Method != recorded.Method || in.Request.URL != recorded.URL || !r.matchBody(in.Request, recorded) {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			Status:        strconv.Itoa(in.Response.StatusCode) + " " + http.StatusText(in.Response.StatusCode),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader([]byte(in.Response.Body))),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	r.t.Errorf("clienttest: no recorded interaction for %s %s in %s", recorded.Method, recorded.URL, r.path)
+	return nil, fmt.Errorf("clienttest: no recorded interaction for %s %s", recorded.Method, recorded.URL)
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// normalizeMultipart заменяет случайную границу multipart-тела постоянной.
+func normalizeMultipart(h http.Header, body []byte) (http.Header, string) {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return h, string(body)
+	}
+	boundary := params["boundary"]
+	params["boundary"] = multipartBoundary
+	h.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	return h, strings.ReplaceAll(string(body), boundary, multipartBoundary)
+}
+
+func redact(h http.Header) http.Header {
+	h = h.Clone()
+	for _, key := range redactedHeaders {
+		h.Del(key)
+	}
+	if len(h) == 0 {
+		return nil
+	}
+	return h
+}
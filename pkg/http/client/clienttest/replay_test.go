@@ -0,0 +1,162 @@
+package clienttest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mlplabs/common-go-pkg/pkg/http/client"
+)
+
+// newUpstream answers every request with the method, path and body it received.
+func newUpstream(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		json.NewEncoder(w).Encode(map[string]string{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"size":   strings.Repeat("x", len(body)),
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "items.json")
+	upstream := newUpstream(t)
+
+	// record against the upstream
+	tb := &fakeTB{TB: t}
+	rec := NewRecorder(tb, golden, ModeRecord, nil)
+	c := client.NewClient("orders", "items", upstream.URL,
+		client.WithRoundTripper(rec), client.WithHeader("Authorization", "Bearer secret"))
+	recorded := make([]map[string]string, 2)
+	if _, err := c.Get(context.Background(), "/items/1", &client.RequestParams{ResponseBody: &recorded[0]}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Post(context.Background(), "/items", &client.RequestParams{
+		RequestBody:  map[string]string{"name": "pen"},
+		ResponseBody: &recorded[1],
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tb.finish()
+	if failures := tb.failures(); len(failures) > 0 {
+		t.Fatal(failures)
+	}
+
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Fatalf("golden file contains redacted headers:\n%s", data)
+	}
+
+	// replay without the upstream
+	upstream.Close()
+	rec = NewRecorder(t, golden, ModeReplay, nil)
+	c = client.NewClient("orders", "items", "http://items.invalid", client.WithRoundTripper(rec))
+	replayed := make([]map[string]string, 2)
+	if _, err := c.Post(context.Background(), "/items", &client.RequestParams{
+		RequestBody:  map[string]string{"name": "pen"},
+		ResponseBody: &replayed[1],
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(context.Background(), "/items/1", &client.RequestParams{ResponseBody: &replayed[0]}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range recorded {
+		if recorded[i]["path"] == "" || recorded[i]["path"] != replayed[i]["path"] || recorded[i]["size"] != replayed[i]["size"] {
+			t.Fatalf("interaction %d: recorded %v, replayed %v", i, recorded[i], replayed[i])
+		}
+	}
+}
+
+func TestReplayMultipart(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "upload.json")
+	upload := func(c *client.Client) map[string]string {
+		var got map[string]string
+		body := client.NewMultipart().Field("name", "report").File("file", "report.txt", strings.NewReader("content"))
+		if _, err := c.Post(context.Background(), "/upload", &client.RequestParams{RequestBody: body, ResponseBody: &got}); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	tb := &fakeTB{TB: t}
+	rec := NewRecorder(tb, golden, ModeRecord, nil)
+	recorded := upload(client.NewClient("orders", "files", newUpstream(t).URL, client.WithRoundTripper(rec)))
+	tb.finish()
+
+	// the new body has another random boundary
+	rec = NewRecorder(t, golden, ModeReplay, nil)
+	replayed := upload(client.NewClient("orders", "files", "http://files.invalid", client.WithRoundTripper(rec)))
+	if recorded["size"] == "" || recorded["size"] != replayed["size"] {
+		t.Fatalf("recorded %v, replayed %v", recorded, replayed)
+	}
+}
+
+func TestReplayBodyMatcher(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "items.json")
+	interactions := []Interaction{{
+		Request:  RecordedRequest{Method: http.MethodPost, URL: "/items", Body: `{"name": "pen", "price": 10}`},
+		Response: RecordedResponse{StatusCode: http.StatusCreated, Body: `{"id":1}`},
+	}}
+	data, _ := json.Marshal(interactions)
+	if err := os.WriteFile(golden, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	post := func(rec *Recorder) error {
+		c := client.NewClient("orders", "items", "http://items.invalid", client.WithRoundTripper(rec))
+		_, err := c.Post(context.Background(), "/items", &client.RequestParams{
+			RequestBody: map[string]interface{}{"price": 10, "name": "pen"},
+		})
+		return err
+	}
+
+	tb := &fakeTB{TB: t}
+	if err := post(NewRecorder(tb, golden, ModeReplay, nil)); err == nil {
+		t.Fatal("exact matcher matched a differently formatted body")
+	}
+	if len(tb.failures()) != 1 {
+		t.Fatalf("missing interaction is not reported: %q", tb.failures())
+	}
+
+	if err := post(NewRecorder(t, golden, ModeReplay, nil).MatchBody(JSONBody)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecorderKeepsRequest(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "items.json")
+	tb := &fakeTB{TB: t}
+	rec := NewRecorder(tb, golden, ModeRecord, nil)
+	defer tb.finish()
+
+	body := io.NopCloser(strings.NewReader(`{"name":"pen"}`))
+	req := httptest.NewRequest(http.MethodPost, newUpstream(t).URL+"/items", body)
+	req.RequestURI = ""
+	resp, err := rec.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if req.Body != body {
+		t.Fatal("RoundTrip replaced the request body")
+	}
+	if got := rec.Interactions(); len(got) != 1 || got[0].Request.Body != `{"name":"pen"}` {
+		t.Fatalf("interactions: %+v", got)
+	}
+}
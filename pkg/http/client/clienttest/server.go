@@ -0,0 +1,340 @@
+// Package clienttest - заглушки сервисов для тестов кода, использующего client.Client:
+// программируемый сервер с ожиданиями и транспорт записи/воспроизведения запросов.
+package clienttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mlplabs/common-go-pkg/pkg/http/client"
+	httpErrors "github.com/mlplabs/common-go-pkg/pkg/http/errors"
+	"github.com/mlplabs/common-go-pkg/pkg/http/errors/custom"
+	"github.com/mlplabs/common-go-pkg/pkg/http/response/wrapper"
+)
+
+// Call - запрос, полученный сервером.
+type Call struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// Server - программируемый сервер. Запросы сопоставляются с ожиданиями в порядке их объявления,
+// на запрос без ожидания отвечает 501 и отмечает ошибку теста.
+// При завершении теста сервер закрывается и проверяет, что все ожидания выполнены.
+//
+//	srv := clienttest.NewServer(t)
+//	srv.Expect(http.MethodGet, "/items/{id}").RespondData(item)
+//	c := srv.Client("orders", "items")
+type Server struct {
+	*httptest.Server
+
+	t            testing.TB
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+}
+
+// NewServer запускает сервер, закрываемый при завершении теста t.
+func NewServer(t testing.TB) *Server {
+	s := &Server{t: t}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(func() {
+		s.Close()
+		s.AssertExpectations()
+	})
+	return s
+}
+
+// Client создаёт клиента, направленного на сервер.
+func (s *Server) Client(clientName string, ownerServiceName string, opts ...client.Option) *client.Client {
+	return client.NewClient(clientName, ownerServiceName, s.URL, opts...)
+}
+
+// Expect добавляет ожидание запроса method к пути pattern. В pattern допустимы параметры "{id}".
+// По умолчанию ожидается ровно один запрос с ответом 200 без тела.
+func (s *Server) Expect(method string, pattern string) *Expectation {
+	e := &Expectation{
+		method:  method,
+		pattern: splitPath(pattern),
+		times:   1,
+		handler: func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) },
+	}
+	s.mu.Lock()
+	s.expectations = append(s.expectations, e)
+	s.mu.Unlock()
+	return e
+}
+
+// Calls - полученные запросы по порядку.
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// CallCount - число запросов method к пути pattern.
+func (s *Server) CallCount(method string, pattern string) int {
+	parts := splitPath(pattern)
+	n := 0
+	for _, call := range s.Calls() {
+		if call.Method == method && matchPath(parts, call.Path) {
+			n++
+		}
+	}
+	return n
+}
+
+// AssertCalled проверяет, что был хотя бы один запрос method к пути pattern.
+func (s *Server) AssertCalled(method string, pattern string) bool {
+	s.t.Helper()
+	if s.CallCount(method, pattern) == 0 {
+		s.t.Errorf("clienttest: expected call %s %s, got none", method, pattern)
+		return false
+	}
+	return true
+}
+
+// AssertNotCalled проверяет, что запросов method к пути pattern не было.
+func (s *Server) AssertNotCalled(method string, pattern string) bool {
+	s.t.Helper()
+	if n := s.CallCount(method, pattern); n > 0 {
+		s.t.Errorf("clienttest: unexpected %d call(s) %s %s", n, method, pattern)
+		return false
+	}
+	return true
+}
+
+// AssertExpectations проверяет, что каждое ожидание получило объявленное число запросов.
+func (s *Server) AssertExpectations() bool {
+	s.t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ok := true
+	for _, e := range s.expectations {
+		if e.times > 0 && e.calls != e.times {
+			s.t.Errorf("clienttest: %s expected %d call(s), got %d", e, e.times, e.calls)
+			ok = false
+		}
+	}
+	return ok
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		httpErrors.SetError(w, r, err)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	call := Call{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	var matched *Expectation
+	for _, e := range s.expectations {
+		if (e.times <= 0 || e.calls < e.times) && e.match(call) {
+			matched = e
+			e.calls++
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if matched == nil {
+		s.t.Errorf("clienttest: unexpected call %s %s", r.Method, r.URL.RequestURI())
+		httpErrors.SetError(w, r, custom.NewCommonError(
+			http.StatusNotImplemented, "UNEXPECTED_CALL", nil, "unexpected call "+r.Method+" "+r.URL.Path, ""))
+		return
+	}
+	matched.handler(w, r)
+}
+
+// Expectation - ожидаемый запрос и ответ на него.
+type Expectation struct {
+	method  string
+	pattern []string
+	header  http.Header
+	query   url.Values
+	body    interface{}
+	times   int
+	calls   int
+	handler http.HandlerFunc
+}
+
+func (e *Expectation) String() string {
+	return e.method + " /" + strings.Join(e.pattern, "/")
+}
+
+// WithHeader - запрос должен содержать заголовок key со значением value.
+func (e *Expectation) WithHeader(key, value string) *Expectation {
+	if e.header == nil {
+		e.header = http.Header{}
+	}
+	e.header.Add(key, value)
+	return e
+}
+
+// WithQuery - запрос должен содержать параметр key со значением value.
+func (e *Expectation) WithQuery(key, value string) *Expectation {
+	if e.query == nil {
+		e.query = url.Values{}
+	}
+	e.query.Add(key, value)
+	return e
+}
+
+// WithJSON - тело запроса должно совпадать с v как JSON.
+func (e *Expectation) WithJSON(v interface{}) *Expectation {
+	e.body = v
+	return e
+}
+
+// Times - ожидаемое число запросов, 0 - любое.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// RespondWith - ответ обработчиком handler.
+func (e *Expectation) RespondWith(handler http.HandlerFunc) *Expectation {
+	e.handler = handler
+	return e
+}
+
+// Respond - ответ со статусом status и телом body в JSON. При body == nil тело пустое.
+func (e *Expectation) Respond(status int, body interface{}) *Expectation {
+	return e.RespondWith(func(w http.ResponseWriter, _ *http.Request) {
+		if body == nil {
+			w.WriteHeader(status)
+			return
+		}
+		writeJSON(w, status, body)
+	})
+}
+
+// RespondData - ответ 200 в конверте wrapper.Data.
+func (e *Expectation) RespondData(data interface{}) *Expectation {
+	return e.Respond(http.StatusOK, wrapper.Data{Data: data})
+}
+
+// RespondList - ответ 200 в конверте wrapper.List.
+func (e *Expectation) RespondList(data interface{}, count int) *Expectation {
+	return e.Respond(http.StatusOK, wrapper.List{Data: data, Count: count})
+}
+
+// RespondPagination - ответ 200 в конверте wrapper.Pagination.
+func (e *Expectation) RespondPagination(data interface{}, count, limit, offset int) *Expectation {
+	return e.Respond(http.StatusOK, wrapper.Pagination{
+		Data:      data,
+		DataRange: wrapper.DataRange{Count: count, Limit: limit, Offset: offset},
+	})
+}
+
+// RespondError - ответ ошибкой err в конверте errors.ResponseError, как её отдал бы errors.SetError.
+func (e *Expectation) RespondError(err error) *Expectation {
+	return e.RespondWith(func(w http.ResponseWriter, r *http.Request) {
+		httpErrors.SetError(w, r, err)
+	})
+}
+
+// RespondErrorCode - ответ ошибкой с кодом code в конверте errors.ResponseError.
+func (e *Expectation) RespondErrorCode(status int, code string, message string) *Expectation {
+	return e.Respond(status, httpErrors.ResponseError{
+		Error: httpErrors.Response{Code: code, Message: message},
+	})
+}
+
+func (e *Expectation) match(call Call) bool {
+	if e.method != call.Method || !matchPath(e.pattern, call.Path) {
+		return false
+	}
+	for key, values := range e.header {
+		for _, v := range values {
+			if !contains(call.Header.Values(key), v) {
+				return false
+			}
+		}
+	}
+	for key, values := range e.query {
+		for _, v := range values {
+			if !contains(call.Query[key], v) {
+				return false
+			}
+		}
+	}
+	if e.body != nil {
+		return jsonEqual(e.body, call.Body)
+	}
+	return true
+}
+
+func splitPath(path string) []string {
+	path, _, _ = strings.Cut(path, "?")
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func matchPath(pattern []string, path string) bool {
+	parts := splitPath(path)
+	if len(parts) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			continue
+		}
+		if p != parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonEqual(expected interface{}, body []byte) bool {
+	want, err := json.Marshal(expected)
+	if err != nil {
+		return false
+	}
+	var a, b interface{}
+	if json.Unmarshal(want, &a) != nil || json.Unmarshal(body, &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		panic(fmt.Sprintf("clienttest: marshal response: %v", err))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -0,0 +1,158 @@
+package clienttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/mlplabs/common-go-pkg/pkg/http/client"
+	"github.com/mlplabs/common-go-pkg/pkg/http/errors/custom"
+)
+
+// fakeTB collects failures and cleanups instead of failing the real test.
+type fakeTB struct {
+	testing.TB
+
+	mu       sync.Mutex
+	errors   []string
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Fatalf(format string, args ...interface{}) {
+	f.Errorf(format, args...)
+}
+
+func (f *fakeTB) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+// finish runs the cleanups as the end of a test would.
+func (f *fakeTB) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+	f.cleanups = nil
+}
+
+func (f *fakeTB) failures() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.errors...)
+}
+
+type item struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestServerRespondData(t *testing.T) {
+	srv := NewServer(t)
+	srv.Expect(http.MethodGet, "/items/{id}").
+		WithHeader("X-Service-Name", "orders").
+		WithQuery("expand", "price").
+		RespondData(item{ID: 7, Name: "pen"})
+	c := srv.Client("orders", "items")
+
+	var got struct {
+		Data item `json:"data"`
+	}
+	_, err := c.Get(context.Background(), "/items/{id}", &client.RequestParams{
+		PathParams:   map[string]string{"id": "7"},
+		Query:        map[string]string{"expand": "price"},
+		ResponseBody: &got,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Data != (item{ID: 7, Name: "pen"}) {
+		t.Fatalf("got %+v", got.Data)
+	}
+	srv.AssertCalled(http.MethodGet, "/items/{id}")
+	srv.AssertNotCalled(http.MethodDelete, "/items/{id}")
+	if calls := srv.Calls(); len(calls) != 1 || calls[0].Path != "/items/7" {
+		t.Fatalf("calls: %+v", calls)
+	}
+}
+
+func TestServerWithJSON(t *testing.T) {
+	srv := NewServer(t)
+	srv.Expect(http.MethodPost, "/items").
+		WithJSON(item{ID: 1, Name: "pen"}).
+		Respond(http.StatusCreated, item{ID: 1, Name: "pen"})
+	c := srv.Client("orders", "items")
+
+	var got item
+	resp, err := c.Post(context.Background(), "/items", &client.RequestParams{
+		RequestBody:  map[string]interface{}{"name": "pen", "id": 1},
+		ResponseBody: &got,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated || got.Name != "pen" {
+		t.Fatalf("status %d, got %+v", resp.StatusCode, got)
+	}
+}
+
+func TestServerRespondError(t *testing.T) {
+	srv := NewServer(t)
+	srv.Expect(http.MethodGet, "/items/{id}").RespondErrorCode(http.StatusNotFound, "ITEM_NOT_FOUND", "нет товара")
+	srv.Expect(http.MethodDelete, "/items/{id}").RespondError(custom.NewForbidden(nil))
+	c := srv.Client("orders", "items")
+
+	_, err := c.Get(context.Background(), "/items/1", nil)
+	var common *custom.CommonError
+	if !errors.As(err, &common) || common.StatusCode() != http.StatusNotFound || common.ErrorCode() != "ITEM_NOT_FOUND" {
+		t.Fatalf("got %v", err)
+	}
+	_, err = c.Delete(context.Background(), "/items/1", nil)
+	if !errors.As(err, &common) || common.StatusCode() != http.StatusForbidden || common.ErrorCode() != "FORBIDDEN" {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestServerTimes(t *testing.T) {
+	srv := NewServer(t)
+	srv.Expect(http.MethodGet, "/ping").Times(3)
+	c := srv.Client("orders", "items")
+
+	for i := 0; i < 3; i++ {
+		resp, err := c.Get(context.Background(), "/ping", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if n := srv.CallCount(http.MethodGet, "/ping"); n != 3 {
+		t.Fatalf("call count %d", n)
+	}
+}
+
+func TestServerReportsMismatches(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	srv := NewServer(tb)
+	srv.Expect(http.MethodGet, "/items/{id}")
+	c := srv.Client("orders", "items")
+
+	_, err := c.Post(context.Background(), "/items", nil)
+	var common *custom.CommonError
+	if !errors.As(err, &common) || common.StatusCode() != http.StatusNotImplemented {
+		t.Fatalf("got %v", err)
+	}
+	tb.finish()
+
+	if failures := tb.failures(); len(failures) != 2 {
+		t.Fatalf("want unexpected call and unmet expectation, got %q", failures)
+	}
+}
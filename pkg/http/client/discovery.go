@@ -0,0 +1,392 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultRefreshInterval = 30 * time.Second
+	defaultEjectAfter      = 5
+	defaultEjectFor        = 30 * time.Second
+	hashRingReplicas       = 100
+)
+
+// ErrNoEndpoints - не найдено ни одного адреса сервиса.
+var ErrNoEndpoints = errors.New("client: no endpoints resolved")
+
+// Resolver - источник адресов экземпляров сервиса вида "http://10.0.0.1:8080".
+type Resolver interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// ResolverFunc - функция как Resolver.
+type ResolverFunc func(ctx context.Context) ([]string, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// StaticResolver - фиксированный список адресов.
+func StaticResolver(urls ...string) Resolver {
+	return ResolverFunc(func(context.Context) ([]string, error) {
+		return urls, nil
+	})
+}
+
+// DNSSRVResolver - адреса из SRV-записи _service._proto.name.
+func DNSSRVResolver(scheme, service, proto, name string) Resolver {
+	return ResolverFunc(func(ctx context.Context) ([]string, error) {
+		_, records, err := net.DefaultResolver.LookupSRV(ctx, service, proto, name)
+		if err != nil {
+			return nil, err
+		}
+		urls := make([]string, 0, len(records))
+		for _, r := range records {
+			host := strings.TrimSuffix(r.Target, ".")
+			urls = append(urls, scheme+"://"+net.JoinHostPort(host, strconv.Itoa(int(r.Port))))
+		}
+		return urls, nil
+	})
+}
+
+// DNSResolver - адреса из A/AAAA-записей host с портом port.
+func DNSResolver(scheme, host, port string) Resolver {
+	return ResolverFunc(func(ctx context.Context) ([]string, error) {
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		urls := make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			urls = append(urls, scheme+"://"+net.JoinHostPort(addr, port))
+		}
+		return urls, nil
+	})
+}
+
+// FileResolver - адреса из файла, по одному на строку. Пустые строки и строки с # пропускаются.
+func FileResolver(path string) Resolver {
+	return ResolverFunc(func(context.Context) ([]string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var urls []string
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				urls = append(urls, line)
+			}
+		}
+		return urls, nil
+	})
+}
+
+// Endpoint - экземпляр сервиса.
+type Endpoint struct {
+	URL *url.URL
+
+	inFlight     atomic.Int64
+	failures     atomic.Int64 // ошибок подряд
+	ejectedUntil atomic.Int64 // unix nano
+}
+
+// InFlight - число выполняющихся запросов к экземпляру.
+func (e *Endpoint) InFlight() int64 {
+	return e.inFlight.Load()
+}
+
+func (e *Endpoint) ejected(now time.Time) bool {
+	return now.UnixNano() < e.ejectedUntil.Load()
+}
+
+// Balancer - выбор экземпляра для запроса из доступных.
+type Balancer interface {
+	Pick(req *http.Request, endpoints []*Endpoint) *Endpoint
+}
+
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+// RoundRobin - экземпляры по очереди.
+func RoundRobin() Balancer {
+	return &roundRobin{}
+}
+
+func (b *roundRobin) Pick(_ *http.Request, endpoints []*Endpoint) *Endpoint {
+	return endpoints[(b.next.Add(1)-1)%uint64(len(endpoints))]
+}
+
+type leastLoaded struct {
+	next atomic.Uint64
+}
+
+// LeastLoaded - экземпляр с наименьшим числом выполняющихся запросов.
+func LeastLoaded() Balancer {
+	return &leastLoaded{}
+}
+
+func (b *leastLoaded) Pick(_ *http.Request, endpoints []*Endpoint) *Endpoint {
+	// начинаем с разных экземпляров, чтобы при равной нагрузке не выбирать всегда первый
+	start := int(b.next.Add(1) % uint64(len(endpoints)))
+	best := endpoints[start]
+	for i := 1; i < len(endpoints); i++ {
+		e := endpoints[(start+i)%len(endpoints)]
+		if e.InFlight() < best.InFlight() {
+			best = e
+		}
+	}
+	return best
+}
+
+type hashRing struct {
+	key    string
+	hashes []uint32
+	owners map[uint32]*Endpoint
+}
+
+type consistentHash struct {
+	key  func(req *http.Request) string
+	mu   sync.Mutex
+	ring *hashRing
+}
+
+// ConsistentHash - запросы с одинаковым ключом попадают на один экземпляр,
+// при изменении списка экземпляров перераспределяется минимум ключей.
+// По умолчанию ключ - путь запроса.
+func ConsistentHash(key func(req *http.Request) string) Balancer {
+	if key == nil {
+		key = func(req *http.Request) string { return req.URL.Path }
+	}
+	return &consistentHash{key: key}
+}
+
+func (b *consistentHash) Pick(req *http.Request, endpoints []*Endpoint) *Endpoint {
+	ring := b.ringFor(endpoints)
+	h := hash32(b.key(req))
+	i := sort.Search(len(ring.hashes), func(i int) bool { return ring.hashes[i] >= h })
+	if i == len(ring.hashes) {
+		i = 0
+	}
+	return ring.owners[ring.hashes[i]]
+}
+
+func (b *consistentHash) ringFor(endpoints []*Endpoint) *hashRing {
+	names := make([]string, len(endpoints))
+	for i, e := range endpoints {
+		names[i] = e.URL.String()
+	}
+	key := strings.Join(names, ",")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ring != nil && b.ring.key == key {
+		return b.ring
+	}
+
+	ring := &hashRing{key: key, owners: make(map[uint32]*Endpoint, len(endpoints)*hashRingReplicas)}
+	for _, e := range endpoints {
+		for r := 0; r < hashRingReplicas; r++ {
+			h := hash32(e.URL.String() + "#" + strconv.Itoa(r))
+			ring.hashes = append(ring.hashes, h)
+			ring.owners[h] = e
+		}
+	}
+	slices.Sort(ring.hashes)
+	b.ring = ring
+	return ring
+}
+
+func hash32(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+// DiscoveryConfig - настройки обнаружения экземпляров сервиса.
+type DiscoveryConfig struct {
+	Resolver        Resolver
+	Balancer        Balancer      // по умолчанию RoundRobin
+	RefreshInterval time.Duration // период повторного получения адресов
+	EjectAfter      int           // ошибок подряд (сетевых или 5xx) до исключения экземпляра
+	EjectFor        time.Duration // на сколько исключать экземпляр
+}
+
+// Discovery - актуальный список экземпляров сервиса с балансировкой.
+type Discovery struct {
+	cfg       DiscoveryConfig
+	endpoints atomic.Pointer[[]*Endpoint]
+	stop      context.CancelFunc
+}
+
+// NewDiscovery получает адреса и запускает их периодическое обновление до ctx.Done() или Close.
+func NewDiscovery(ctx context.Context, cfg DiscoveryConfig) (*Discovery, error) {
+	if cfg.Resolver == nil {
+		return nil, fmt.Errorf("client: discovery resolver is not set")
+	}
+	if cfg.Balancer == nil {
+		cfg.Balancer = RoundRobin()
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = defaultRefreshInterval
+	}
+	if cfg.EjectAfter <= 0 {
+		cfg.EjectAfter = defaultEjectAfter
+	}
+	if cfg.EjectFor <= 0 {
+		cfg.EjectFor = defaultEjectFor
+	}
+
+	d := &Discovery{cfg: cfg}
+	if err := d.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	ctx, d.stop = context.WithCancel(ctx)
+	go d.refreshLoop(ctx)
+	return d, nil
+}
+
+// WithDiscovery - запросы клиента распределяются по экземплярам сервиса.
+// Схема и хост baseURL заменяются адресом выбранного экземпляра.
+func WithDiscovery(d *Discovery) Option {
+	return func(c *Client) {
+		c.discovery = d
+	}
+}
+
+// Close останавливает обновление адресов.
+func (d *Discovery) Close() {
+	d.stop()
+}
+
+// Endpoints - текущий список экземпляров.
+func (d *Discovery) Endpoints() []*Endpoint {
+	if eps := d.endpoints.Load(); eps != nil {
+		return *eps
+	}
+	return nil
+}
+
+// Refresh получает адреса заново. Состояние уже известных экземпляров сохраняется.
+func (d *Discovery) Refresh(ctx context.Context) error {
+	urls, err := d.cfg.Resolver.Resolve(ctx)
+	if err != nil {
+		return fmt.Errorf("client: resolve endpoints: %w", err)
+	}
+	if len(urls) == 0 {
+		return ErrNoEndpoints
+	}
+
+	known := make(map[string]*Endpoint)
+	for _, e := range d.Endpoints() {
+		known[e.URL.String()] = e
+	}
+	endpoints := make([]*Endpoint, 0, len(urls))
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("client: resolve endpoints: %w", err)
+		}
+		if e, ok := known[u.String()]; ok {
+			endpoints = append(endpoints, e)
+			continue
+		}
+		endpoints = append(endpoints, &Endpoint{URL: u})
+	}
+	slices.SortFunc(endpoints, func(a, b *Endpoint) int { return strings.Compare(a.URL.String(), b.URL.String()) })
+	d.endpoints.Store(&endpoints)
+	return nil
+}
+
+func (d *Discovery) refreshLoop(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// при ошибке остаётся прежний список
+			if err := d.Refresh(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("ERROR: %v", err)
+			}
+		}
+	}
+}
+
+func (d *Discovery) pick(req *http.Request) (*Endpoint, error) {
+	all := d.Endpoints()
+	if len(all) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	now := time.Now()
+	healthy := make([]*Endpoint, 0, len(all))
+	for _, e := range all {
+		if !e.ejected(now) {
+			healthy = append(healthy, e)
+		}
+	}
+	if len(healthy) == 0 {
+		// все исключены - лучше попробовать, чем отказать
+		healthy = all
+	}
+	return d.cfg.Balancer.Pick(req, healthy), nil
+}
+
+func (d *Discovery) report(e *Endpoint, failed bool) {
+	if !failed {
+		e.failures.Store(0)
+		return
+	}
+	if e.failures.Add(1) >= int64(d.cfg.EjectAfter) {
+		e.failures.Store(0)
+		e.ejectedUntil.Store(time.Now().Add(d.cfg.EjectFor).UnixNano())
+	}
+}
+
+// sendTo отправляет попытку запроса выбранному экземпляру сервиса.
+func (c *Client) sendTo(req *http.Request) (*http.Response, error) {
+	if c.discovery == nil {
+		return c.send(req)
+	}
+	e, err := c.discovery.pick(req)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.URL.Scheme = e.URL.Scheme
+	req.URL.Host = e.URL.Host
+	req.Host = ""
+
+	e.inFlight.Add(1)
+	resp, err := c.send(req)
+	// отмена запроса вызывающим не говорит о неисправности экземпляра
+	if req.Context().Err() == nil {
+		c.discovery.report(e, err != nil || resp.StatusCode >= http.StatusInternalServerError)
+	}
+	if resp == nil || resp.Body == nil {
+		e.inFlight.Add(-1)
+		return resp, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: func() { e.inFlight.Add(-1) }}
+	return resp, err
+}
@@ -0,0 +1,206 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer counts requests, answers /fail with 500 and holds /slow until the request is cancelled.
+type countingServer struct {
+	*httptest.Server
+	hits atomic.Int64
+	fail atomic.Bool
+}
+
+func newCountingServer(t *testing.T) *countingServer {
+	s := &countingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		switch {
+		case s.fail.Load():
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":{"code":"INTERNAL","message":"fail"}}`))
+		case r.URL.Path == "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// switchResolver is a Resolver with a replaceable list of addresses.
+type switchResolver struct {
+	mu    sync.Mutex
+	urls  []string
+	calls int
+}
+
+func (r *switchResolver) Resolve(context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	return r.urls, nil
+}
+
+func (r *switchResolver) set(urls ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.urls = urls
+}
+
+func (r *switchResolver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+func newTestDiscovery(t *testing.T, cfg DiscoveryConfig) *Discovery {
+	d, err := NewDiscovery(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(d.Close)
+	return d
+}
+
+func TestDiscoveryRoundRobin(t *testing.T) {
+	a, b := newCountingServer(t), newCountingServer(t)
+	d := newTestDiscovery(t, DiscoveryConfig{Resolver: StaticResolver(a.URL, b.URL)})
+	c := NewClient("test", "svc", "http://svc", WithDiscovery(d))
+
+	for i := 0; i < 10; i++ {
+		resp, err := c.Get(context.Background(), "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if a.hits.Load() != 5 || b.hits.Load() != 5 {
+		t.Fatalf("hits: a=%d b=%d, want 5 and 5", a.hits.Load(), b.hits.Load())
+	}
+	for _, e := range d.Endpoints() {
+		if e.InFlight() != 0 {
+			t.Fatalf("%s: %d requests in flight", e.URL, e.InFlight())
+		}
+	}
+}
+
+func TestDiscoveryEjectsFailingEndpoint(t *testing.T) {
+	a, b := newCountingServer(t), newCountingServer(t)
+	a.fail.Store(true)
+	d := newTestDiscovery(t, DiscoveryConfig{
+		Resolver:   StaticResolver(a.URL, b.URL),
+		EjectAfter: 2,
+		EjectFor:   time.Minute,
+	})
+	c := NewClient("test", "svc", "http://svc", WithDiscovery(d))
+
+	for i := 0; i < 4; i++ {
+		c.Get(context.Background(), "/", nil)
+	}
+	if a.hits.Load() != 2 {
+		t.Fatalf("failing endpoint got %d requests before ejection, want 2", a.hits.Load())
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := c.Get(context.Background(), "/", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a.hits.Load() != 2 || b.hits.Load() != 6 {
+		t.Fatalf("hits after ejection: a=%d b=%d, want 2 and 6", a.hits.Load(), b.hits.Load())
+	}
+}
+
+func TestDiscoveryIgnoresCancelledRequests(t *testing.T) {
+	a, b := newCountingServer(t), newCountingServer(t)
+	d := newTestDiscovery(t, DiscoveryConfig{
+		Resolver:   StaticResolver(a.URL, b.URL),
+		EjectAfter: 1,
+		EjectFor:   time.Minute,
+	})
+	c := NewClient("test", "svc", "http://svc", WithDiscovery(d))
+
+	// first request goes to a and is cancelled by its timeout, the second one to b
+	_, err := c.Get(context.Background(), "/slow", &RequestParams{Timeout: 20 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want deadline exceeded, got %v", err)
+	}
+	if _, err := c.Get(context.Background(), "/", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := c.Get(context.Background(), "/", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a.hits.Load() != 3 || b.hits.Load() != 3 {
+		t.Fatalf("cancelled request ejected the endpoint: a=%d b=%d, want 3 and 3", a.hits.Load(), b.hits.Load())
+	}
+}
+
+func TestDiscoveryRefresh(t *testing.T) {
+	a, b := newCountingServer(t), newCountingServer(t)
+	resolver := &switchResolver{urls: []string{a.URL}}
+	d := newTestDiscovery(t, DiscoveryConfig{Resolver: resolver, RefreshInterval: time.Hour})
+	c := NewClient("test", "svc", "http://svc", WithDiscovery(d))
+
+	resolver.set(b.URL)
+	if err := d.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(context.Background(), "/", nil); err != nil {
+		t.Fatal(err)
+	}
+	if a.hits.Load() != 0 || b.hits.Load() != 1 {
+		t.Fatalf("hits: a=%d b=%d, want 0 and 1", a.hits.Load(), b.hits.Load())
+	}
+
+	// an empty result keeps the previous list
+	resolver.set()
+	if err := d.Refresh(context.Background()); !errors.Is(err, ErrNoEndpoints) {
+		t.Fatalf("want ErrNoEndpoints, got %v", err)
+	}
+	if eps := d.Endpoints(); len(eps) != 1 || eps[0].URL.String() != b.URL {
+		t.Fatalf("endpoints after failed refresh: %v", eps)
+	}
+}
+
+func TestDiscoveryStopsWithContext(t *testing.T) {
+	srv := newCountingServer(t)
+	resolver := &switchResolver{urls: []string{srv.URL}}
+	ctx, cancel := context.WithCancel(context.Background())
+	d, err := NewDiscovery(ctx, DiscoveryConfig{Resolver: resolver, RefreshInterval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	time.Sleep(30 * time.Millisecond)
+	if resolver.count() < 2 {
+		t.Fatalf("addresses were not refreshed, %d calls", resolver.count())
+	}
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	calls := resolver.count()
+	time.Sleep(30 * time.Millisecond)
+	if resolver.count() != calls {
+		t.Fatalf("refresh continued after the context was cancelled: %d calls, want %d", resolver.count(), calls)
+	}
+}
+
+func TestDiscoveryNoEndpoints(t *testing.T) {
+	_, err := NewDiscovery(context.Background(), DiscoveryConfig{Resolver: StaticResolver()})
+	if !errors.Is(err, ErrNoEndpoints) {
+		t.Fatalf("want ErrNoEndpoints, got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// deadlineHeader совпадает с middleware.DeadlineHeader, клиент не зависит от серверных middleware
+	deadlineHeader = "X-Request-Timeout-Ms"
+
+	defaultHedgeDelay = 100 * time.Millisecond
+)
+
+// HedgePolicy - политика параллельных (hedged) запросов.
+// Если ответ не получен за Delay, отправляется ещё одна копия запроса, используется первый успешный ответ.
+// Применяется только к идемпотентным запросам.
+type HedgePolicy struct {
+	Delay     time.Duration // через сколько отправлять следующую копию, например p95 задержки, по умолчанию 100мс
+	MaxHedges int           // дополнительных копий, по умолчанию 1
+}
+
+// WithHedging - hedged-запросы по политике policy.
+func WithHedging(policy HedgePolicy) Option {
+	return func(c *Client) {
+		if policy.MaxHedges <= 0 {
+			policy.MaxHedges = 1
+		}
+		if policy.Delay <= 0 {
+			policy.Delay = defaultHedgeDelay
+		}
+		c.hedge = &policy
+	}
+}
+
+type hedgeResult struct {
+	idx    int
+	resp   *http.Response
+	err    error
+	cancel context.CancelFunc
+}
+
+func (r hedgeResult) ok() bool {
+	return r.err == nil && r.resp.StatusCode < http.StatusInternalServerError
+}
+
+func (r hedgeResult) discard() {
+	if r.resp != nil {
+		r.resp.Body.Close()
+	}
+	r.cancel()
+}
+
+// doHedged выполняет попытку запроса, при необходимости дублируя её.
+func (c *Client) doHedged(req *http.Request) (*http.Response, error) {
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	if c.hedge == nil || !isIdempotent(req) || !replayable {
+		return c.doWithLimiter(req)
+	}
+
+	total := c.hedge.MaxHedges + 1
+	results := make(chan hedgeResult, total)
+	cancels := make([]context.CancelFunc, 0, total)
+	launch := func() error {
+		ctx, cancel := context.WithCancel(req.Context())
+		hedgeReq := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return err
+			}
+			hedgeReq.Body = body
+		}
+		cancels = append(cancels, cancel)
+		idx := len(cancels) - 1
+		go func() {
+			resp, err := c.doWithLimiter(hedgeReq)
+			results <- hedgeResult{idx: idx, resp: resp, err: err, cancel: cancel}
+		}()
+		return nil
+	}
+	// winner отменяет остальные копии, их ответы закрываются по мере получения
+	winner := func(res hedgeResult, pending int) (*http.Response, error) {
+		for i, cancel := range cancels {
+			if i != res.idx {
+				cancel()
+			}
+		}
+		go drainHedges(results, pending)
+		return finishHedge(res)
+	}
+
+	if err := launch(); err != nil {
+		return nil, err
+	}
+	received := 0
+	timer := time.NewTimer(c.hedge.Delay)
+	defer timer.Stop()
+
+	var last *hedgeResult
+	for {
+		select {
+		case <-timer.C:
+			if len(cancels) < total && launch() == nil {
+				timer.Reset(c.hedge.Delay)
+			}
+		case res := <-results:
+			received++
+			if res.ok() {
+				if last != nil {
+					last.discard()
+				}
+				return winner(res, len(cancels)-received)
+			}
+			if last != nil {
+				last.discard()
+			}
+			last = &res
+			// неудачная копия - следующая отправляется сразу
+			if len(cancels) < total && launch() == nil {
+				timer.Reset(c.hedge.Delay)
+			} else if received == len(cancels) {
+				return winner(res, 0)
+			}
+		}
+	}
+}
+
+// finishHedge привязывает отмену контекста копии к закрытию тела ответа.
+func finishHedge(res hedgeResult) (*http.Response, error) {
+	if res.resp == nil || res.resp.Body == nil {
+		res.cancel()
+		return res.resp, res.err
+	}
+	res.resp.Body = &cancelBody{ReadCloser: res.resp.Body, cancel: res.cancel}
+	return res.resp, res.err
+}
+
+func drainHedges(results <-chan hedgeResult, pending int) {
+	for ; pending > 0; pending-- {
+		(<-results).discard()
+	}
+}
+
+// send отправляет попытку запроса транспортом клиента.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	setDeadlineHeader(req)
+	return c.client.Do(req)
+}
+
+// setDeadlineHeader передаёт сервису оставшееся до дедлайна контекста время.
+func setDeadlineHeader(req *http.Request) {
+	deadline, ok := req.Context().Deadline()
+	if !ok {
+		return
+	}
+	remaining := max(time.Until(deadline).Milliseconds(), 0)
+	req.Header.Set(deadlineHeader, strconv.FormatInt(remaining, 10))
+}
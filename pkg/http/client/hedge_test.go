@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// scriptedTransport answers the n-th request with statuses[n] after delays[n]. The delay
+// ignores cancellation, as a response may arrive right when its copy is cancelled.
+type scriptedTransport struct {
+	calls    atomic.Int64
+	open     atomic.Int64 // response bodies not closed yet
+	cancels  atomic.Int64 // requests whose context was cancelled
+	statuses []int
+	delays   []time.Duration
+}
+
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	n := s.calls.Add(1) - 1
+	time.Sleep(s.delays[n])
+	go func() {
+		<-req.Context().Done()
+		s.cancels.Add(1)
+	}()
+	s.open.Add(1)
+	return &http.Response{
+		StatusCode: s.statuses[n],
+		Header:     http.Header{},
+		Body:       &trackedBody{Reader: strings.NewReader(strconv.Itoa(int(n))), open: &s.open},
+		Request:    req,
+	}, nil
+}
+
+type trackedBody struct {
+	io.Reader
+	open   *atomic.Int64
+	closed atomic.Bool
+}
+
+func (b *trackedBody) Close() error {
+	if b.closed.CompareAndSwap(false, true) {
+		b.open.Add(-1)
+	}
+	return nil
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHedgeFasterCopyWins(t *testing.T) {
+	rt := &scriptedTransport{
+		statuses: []int{http.StatusOK, http.StatusOK},
+		delays:   []time.Duration{100 * time.Millisecond, 0},
+	}
+	c := NewClient("test", "svc", "http://svc", WithRoundTripper(rt), WithHedging(HedgePolicy{Delay: 10 * time.Millisecond}))
+
+	resp, err := c.Get(context.Background(), "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "1" {
+		t.Fatalf("got the response of copy %s, want the hedge", body)
+	}
+
+	// the slow copy is cancelled and its late response is closed
+	waitFor(t, "the late response was not closed", func() bool { return rt.calls.Load() == 2 && rt.open.Load() == 0 })
+	waitFor(t, "the copies were not cancelled", func() bool { return rt.cancels.Load() == 2 })
+}
+
+func TestHedgeFailedCopyLaunchesNext(t *testing.T) {
+	rt := &scriptedTransport{
+		statuses: []int{http.StatusBadGateway, http.StatusOK},
+		delays:   []time.Duration{0, 0},
+	}
+	c := NewClient("test", "svc", "http://svc", WithRoundTripper(rt), WithHedging(HedgePolicy{Delay: time.Second}))
+
+	start := time.Now()
+	resp, err := c.Get(context.Background(), "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("the hedge waited for Delay after a failed copy: %s", time.Since(start))
+	}
+	if rt.open.Load() != 0 {
+		t.Fatalf("%d response bodies are not closed", rt.open.Load())
+	}
+}
+
+func TestHedgeAllCopiesFail(t *testing.T) {
+	rt := &scriptedTransport{
+		statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable},
+		delays:   []time.Duration{0, 0},
+	}
+	c := NewClient("test", "svc", "http://svc", WithRoundTripper(rt), WithHedging(HedgePolicy{Delay: time.Millisecond}))
+
+	_, err := c.Get(context.Background(), "/", nil)
+	if err == nil {
+		t.Fatal("want an error")
+	}
+	if rt.calls.Load() != 2 {
+		t.Fatalf("%d copies, want 2", rt.calls.Load())
+	}
+	waitFor(t, "response bodies are not closed", func() bool { return rt.open.Load() == 0 })
+}
+
+func TestHedgeSkipsNonIdempotent(t *testing.T) {
+	rt := &scriptedTransport{
+		statuses: []int{http.StatusOK, http.StatusOK},
+		delays:   []time.Duration{50 * time.Millisecond, 0},
+	}
+	c := NewClient("test", "svc", "http://svc", WithRoundTripper(rt), WithHedging(HedgePolicy{Delay: time.Millisecond}))
+
+	resp, err := c.Post(context.Background(), "/", &RequestParams{RequestBody: map[string]string{"name": "pen"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if rt.calls.Load() != 1 {
+		t.Fatalf("POST was hedged, %d copies", rt.calls.Load())
+	}
+}
+
+func TestDeadlineHeader(t *testing.T) {
+	var header atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header.Store(r.Header.Get(deadlineHeader))
+	}))
+	t.Cleanup(srv.Close)
+	c := NewClient("test", "svc", srv.URL)
+
+	resp, err := c.Get(context.Background(), "/", &RequestParams{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	ms, err := strconv.Atoi(header.Load().(string))
+	if err != nil || ms <= 0 || ms > 1000 {
+		t.Fatalf("%s: %q", deadlineHeader, header.Load())
+	}
+
+	resp, err = c.Get(context.Background(), "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if v := header.Load().(string); v != "" {
+		t.Fatalf("%s without a deadline: %q", deadlineHeader, v)
+	}
+}
@@ -0,0 +1,343 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	customErrors "github.com/mlplabs/common-go-pkg/pkg/http/errors/custom"
+)
+
+var (
+	// ErrRateLimited - превышена квота запросов к сервису.
+	ErrRateLimited = errors.New("client rate limit exceeded")
+	// ErrTooManyInFlight - превышено число одновременных запросов к сервису.
+	ErrTooManyInFlight = errors.New("client concurrency limit exceeded")
+)
+
+// Limits - ограничения запросов.
+type Limits struct {
+	Rate        float64 // запросов в секунду, 0 - без ограничения
+	Burst       int     // размер корзины токенов, по умолчанию 1
+	MaxInFlight int     // одновременных запросов, 0 - без ограничения
+}
+
+// LimiterConfig - ограничения клиента и отдельных маршрутов.
+type LimiterConfig struct {
+	Limits
+	// Routes - ограничения маршрутов по шаблону пути, переданному в Do: "/items/{id}"
+	// или с методом "POST /items". Действуют вместе с ограничениями клиента.
+	Routes map[string]Limits
+	// FailFast - не ждать освобождения лимита, а сразу возвращать ошибку.
+	FailFast bool
+	// Adaptive - приостанавливать запросы по X-RateLimit-Remaining/X-RateLimit-Reset и Retry-After.
+	Adaptive bool
+}
+
+// WithLimiter - ограничение частоты и числа одновременных запросов.
+// Ограничение применяется к каждой попытке запроса. При отказе возвращается
+// ServiceUnavailable с ErrRateLimited или ErrTooManyInFlight.
+func WithLimiter(cfg LimiterConfig) Option {
+	return func(c *Client) {
+		l := &limiter{
+			cfg:    cfg,
+			client: newLimitSet(cfg.Limits),
+			routes: make(map[string]*limitSet, len(cfg.Routes)),
+		}
+		for route, limits := range cfg.Routes {
+			if method, path, ok := strings.Cut(route, " "); ok {
+				route = method + " " + normalizeRoute(path)
+			} else {
+				route = normalizeRoute(route)
+			}
+			l.routes[route] = newLimitSet(limits)
+		}
+		c.limiter = l
+	}
+}
+
+type limiter struct {
+	cfg    LimiterConfig
+	client *limitSet
+	routes map[string]*limitSet
+}
+
+type limitSet struct {
+	bucket *tokenBucket
+	sem    chan struct{}
+
+	mu     sync.Mutex
+	paused time.Time // до какого момента сервис просил не слать запросы, см. LimiterConfig.Adaptive
+}
+
+func newLimitSet(limits Limits) *limitSet {
+	ls := &limitSet{}
+	if limits.Rate > 0 {
+		ls.bucket = newTokenBucket(limits.Rate, max(limits.Burst, 1))
+	}
+	if limits.MaxInFlight > 0 {
+		ls.sem = make(chan struct{}, limits.MaxInFlight)
+	}
+	return ls
+}
+
+func (ls *limitSet) pause(until time.Time) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if until.After(ls.paused) {
+		ls.paused = until
+	}
+}
+
+func (ls *limitSet) pausedFor(now time.Time) time.Duration {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	return max(ls.paused.Sub(now), 0)
+}
+
+func (l *limiter) sets(method string, route string) []*limitSet {
+	sets := []*limitSet{l.client}
+	if ls, ok := l.routes[method+" "+route]; ok {
+		sets = append(sets, ls)
+	} else if ls, ok := l.routes[route]; ok {
+		sets = append(sets, ls)
+	}
+	return sets
+}
+
+// acquire ждёт разрешения на запрос. release освобождает слоты и учитывает ответ.
+func (l *limiter) acquire(req *http.Request) (release func(resp *http.Response), err error) {
+	ctx := req.Context()
+	sets := l.sets(req.Method, routeFromContext(ctx))
+
+	// пауза по ответам сервиса действует и без ограничения частоты
+	if err := l.waitPause(ctx, sets); err != nil {
+		return nil, err
+	}
+
+	// при отказе одного из ограничений токены, взятые у остальных, возвращаются
+	reserved := make([]*tokenBucket, 0, len(sets))
+	refund := func() {
+		for _, b := range reserved {
+			b.cancel()
+		}
+	}
+	for _, ls := range sets {
+		if ls.bucket == nil {
+			continue
+		}
+		if err := ls.bucket.wait(ctx, l.cfg.FailFast); err != nil {
+			refund()
+			return nil, err
+		}
+		reserved = append(reserved, ls.bucket)
+	}
+
+	acquired := make([]*limitSet, 0, len(sets))
+	releaseSlots := func() {
+		for _, ls := range acquired {
+			<-ls.sem
+		}
+	}
+	for _, ls := range sets {
+		if ls.sem == nil {
+			continue
+		}
+		if l.cfg.FailFast {
+			select {
+			case ls.sem <- struct{}{}:
+			default:
+				releaseSlots()
+				refund()
+				return nil, ErrTooManyInFlight
+			}
+		} else {
+			select {
+			case ls.sem <- struct{}{}:
+			case <-ctx.Done():
+				releaseSlots()
+				refund()
+				return nil, ctx.Err()
+			}
+		}
+		acquired = append(acquired, ls)
+	}
+
+	return func(resp *http.Response) {
+		if resp == nil || resp.Body == nil || len(acquired) == 0 {
+			releaseSlots()
+		} else {
+			// запрос занимает слот, пока не прочитано тело ответа
+			resp.Body = &releaseBody{ReadCloser: resp.Body, release: releaseSlots}
+		}
+		if !l.cfg.Adaptive || resp == nil {
+			return
+		}
+		if until, ok := pauseUntil(resp); ok {
+			for _, ls := range sets {
+				ls.pause(until)
+			}
+		}
+	}, nil
+}
+
+// waitPause ждёт окончания паузы, о которой попросил сервис.
+func (l *limiter) waitPause(ctx context.Context, sets []*limitSet) error {
+	var wait time.Duration
+	now := time.Now()
+	for _, ls := range sets {
+		wait = max(wait, ls.pausedFor(now))
+	}
+	if wait <= 0 {
+		return nil
+	}
+	if l.cfg.FailFast {
+		return ErrRateLimited
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		return ErrRateLimited
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// pauseUntil - до какого момента сервис просит не слать запросы.
+func pauseUntil(resp *http.Response) (time.Time, bool) {
+	if d, ok := retryAfter(resp); ok && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		return time.Now().Add(d), true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return time.Time{}, false
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	// X-RateLimit-Reset бывает как в секундах до сброса, так и unix-временем
+	if reset > time.Now().Unix()/2 {
+		return time.Unix(reset, 0), true
+	}
+	return time.Now().Add(time.Duration(reset) * time.Second), true
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// reserve забирает токен и возвращает, сколько ждать до его появления.
+func (b *tokenBucket) reserve(failFast bool) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	var wait time.Duration
+	if b.tokens < 1 {
+		wait = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	}
+	if wait > 0 && failFast {
+		return wait, false
+	}
+	b.tokens--
+	return wait, true
+}
+
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	b.tokens++
+	b.mu.Unlock()
+}
+
+func (b *tokenBucket) wait(ctx context.Context, failFast bool) error {
+	wait, ok := b.reserve(failFast)
+	if !ok {
+		return ErrRateLimited
+	}
+	if wait <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		b.cancel()
+		return ErrRateLimited
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+type routeKey struct{}
+
+// withRoute сохраняет шаблон пути запроса (до подстановки параметров) для ограничений по маршрутам.
+func withRoute(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, routeKey{}, normalizeRoute(path))
+}
+
+func normalizeRoute(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	return "/" + strings.TrimLeft(path, "/")
+}
+
+func routeFromContext(ctx context.Context) string {
+	route, _ := ctx.Value(routeKey{}).(string)
+	return route
+}
+
+// doWithLimiter выполняет попытку запроса с учётом ограничений клиента.
+func (c *Client) doWithLimiter(req *http.Request) (*http.Response, error) {
+	if c.limiter == nil {
+		return c.doWithBreaker(req)
+	}
+	release, err := c.limiter.acquire(req)
+	if err != nil {
+		if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrTooManyInFlight) {
+			return nil, customErrors.NewServiceUnavailable(err, c.ownerServiceName)
+		}
+		return nil, err
+	}
+	resp, err := c.doWithBreaker(req)
+	release(resp)
+	return resp, err
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mlplabs/common-go-pkg/pkg/http/errors/custom"
+)
+
+// newThrottlingServer answers the first request with 429 and the headers set by throttle, the rest with 200.
+func newThrottlingServer(t *testing.T, throttle func(http.Header)) (*httptest.Server, *atomic.Int64) {
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			throttle(w.Header())
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"code":"TOO_MANY_REQUESTS","message":"slow down"}}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestLimiterAdaptiveWithoutRate(t *testing.T) {
+	for name, limits := range map[string]Limits{
+		"no limits":     {},
+		"only inflight": {MaxInFlight: 5},
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv, hits := newThrottlingServer(t, func(h http.Header) { h.Set("Retry-After", "1") })
+			c := NewClient("test", "svc", srv.URL, WithLimiter(LimiterConfig{Limits: limits, Adaptive: true, FailFast: true}))
+
+			c.Get(context.Background(), "/", nil)
+			_, err := c.Get(context.Background(), "/", nil)
+			var unavailable *custom.ServiceUnavailable
+			if !errors.As(err, &unavailable) || !errors.Is(err, ErrRateLimited) {
+				t.Fatalf("want ErrRateLimited during Retry-After, got %v", err)
+			}
+			if hits.Load() != 1 {
+				t.Fatalf("%d requests reached the service, want 1", hits.Load())
+			}
+		})
+	}
+}
+
+func TestLimiterAdaptiveWaits(t *testing.T) {
+	srv, hits := newThrottlingServer(t, func(h http.Header) {
+		h.Set("X-RateLimit-Remaining", "0")
+		h.Set("X-RateLimit-Reset", "1")
+	})
+	c := NewClient("test", "svc", srv.URL, WithLimiter(LimiterConfig{Adaptive: true}))
+	c.Get(context.Background(), "/", nil)
+
+	// a deadline before the reset is refused at once
+	start := time.Now()
+	_, err := c.Get(context.Background(), "/", &RequestParams{Timeout: 100 * time.Millisecond})
+	if !errors.Is(err, ErrRateLimited) || time.Since(start) > 50*time.Millisecond {
+		t.Fatalf("want ErrRateLimited without waiting, got %v after %s", err, time.Since(start))
+	}
+
+	resp, err := c.Get(context.Background(), "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if time.Since(start) < 500*time.Millisecond || hits.Load() != 2 {
+		t.Fatalf("%d requests, the second one after %s", hits.Load(), time.Since(start))
+	}
+}
+
+func TestLimiterInFlight(t *testing.T) {
+	srv := newEchoServer(t)
+	c := NewClient("test", "echo", srv.URL, WithLimiter(LimiterConfig{Limits: Limits{MaxInFlight: 1}, FailFast: true}))
+
+	// the slot is held until the response body is closed
+	resp, err := c.Get(context.Background(), "/items/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(context.Background(), "/items/2", nil); !errors.Is(err, ErrTooManyInFlight) {
+		t.Fatalf("want ErrTooManyInFlight, got %v", err)
+	}
+	resp.Body.Close()
+	resp, err = c.Get(context.Background(), "/items/2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestLimiterRouteRefundsClientToken(t *testing.T) {
+	srv := newEchoServer(t)
+	c := NewClient("test", "echo", srv.URL, WithLimiter(LimiterConfig{
+		Limits:   Limits{Rate: 0.001, Burst: 2},
+		Routes:   map[string]Limits{"/items/{id}": {Rate: 0.001, Burst: 1}},
+		FailFast: true,
+	}))
+
+	get := func(path string) error {
+		resp, err := c.Get(context.Background(), path, &RequestParams{PathParams: map[string]string{"id": "1"}})
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	if err := get("/items/{id}"); err != nil {
+		t.Fatal(err)
+	}
+	// the route limit refuses the request, the client token is returned
+	if err := get("/items/{id}"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("want ErrRateLimited, got %v", err)
+	}
+	if err := get("/items/1"); err != nil {
+		t.Fatalf("client token was not refunded: %v", err)
+	}
+}
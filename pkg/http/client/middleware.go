@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"time"
+)
+
+// Doer - выполняет HTTP-запрос.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc - функция как Doer.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware - обёртка над выполнением запроса клиента.
+type Middleware func(next Doer) Doer
+
+// WithMiddleware добавляет middleware в цепочку клиента.
+// Middleware выполняются в порядке добавления: первая видит запрос первой, а ответ последней.
+// Цепочка оборачивает весь вызов, включая повторы и circuit breaker,
+// поэтому каждая middleware вызывается один раз на вызов Get/Post/...
+func WithMiddleware(mw ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, mw...)
+	}
+}
+
+// chain собирает цепочку middleware вокруг next.
+func chain(middleware []Middleware, next Doer) Doer {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		next = middleware[i](next)
+	}
+	return next
+}
+
+type attemptHookKey struct{}
+
+func withAttemptHook(ctx context.Context, onAttempt func(Attempt)) context.Context {
+	if onAttempt == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, attemptHookKey{}, onAttempt)
+}
+
+func attemptHook(ctx context.Context) func(Attempt) {
+	onAttempt, _ := ctx.Value(attemptHookKey{}).(func(Attempt))
+	return onAttempt
+}
+
+// Logging - логирование запросов: метод, адрес, код ответа и длительность.
+// logf по умолчанию log.Printf.
+func Logging(logf func(format string, v ...any)) Middleware {
+	if logf == nil {
+		logf = log.Printf
+	}
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.Do(req)
+			if err != nil {
+				logf("client: %s %s: %v (%s)", req.Method, req.URL, err, time.Since(start))
+				return resp, err
+			}
+			logf("client: %s %s: %d (%s)", req.Method, req.URL, resp.StatusCode, time.Since(start))
+			return resp, err
+		})
+	}
+}
+
+type headersKey struct{}
+
+// ContextWithHeaders сохраняет заголовки входящего запроса для PropagateHeaders.
+func ContextWithHeaders(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, headersKey{}, header)
+}
+
+// CaptureHeaders - серверная middleware, сохраняющая заголовки входящего запроса в контекст.
+func CaptureHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(ContextWithHeaders(r.Context(), r.Header)))
+	})
+}
+
+// PropagateHeaders копирует заголовки keys входящего запроса (см. CaptureHeaders) в исходящий,
+// например X-Request-Id для сквозной трассировки.
+func PropagateHeaders(keys ...string) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			incoming, _ := req.Context().Value(headersKey{}).(http.Header)
+			for _, key := range keys {
+				if v := incoming.Values(key); len(v) > 0 && req.Header.Get(key) == "" {
+					req.Header[http.CanonicalHeaderKey(key)] = v
+				}
+			}
+			return next.Do(req)
+		})
+	}
+}
+
+// Dump пишет запросы и ответы целиком в w, для отладки. body включает вывод тел.
+func Dump(w io.Writer, body bool) Middleware {
+	var mu sync.Mutex
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if dump, err := httputil.DumpRequestOut(req, body); err == nil {
+				mu.Lock()
+				fmt.Fprintf(w, "%s\n", dump)
+				mu.Unlock()
+			}
+			resp, err := next.Do(req)
+			if err != nil {
+				return resp, err
+			}
+			if dump, err := httputil.DumpResponse(resp, body); err == nil {
+				mu.Lock()
+				fmt.Fprintf(w, "%s\n", dump)
+				mu.Unlock()
+			}
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/mlplabs/common-go-pkg/pkg/config"
+)
+
+const (
+	defaultDialTimeout = 30 * time.Second
+	defaultKeepAlive   = 30 * time.Second
+)
+
+// Option - настройки HTTP-клиента.
+type Option func(*Client)
+
+// WithRetry - повтор запросов по политике policy.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy.withDefaults()
+	}
+}
+
+// WithTimeout - общий таймаут запроса, включая чтение тела ответа.
+// Для отдельного запроса см. RequestParams.Timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.client.Timeout = timeout
+	}
+}
+
+// WithMaxIdleConns - размер пула простаивающих соединений.
+func WithMaxIdleConns(total int, perHost int) Option {
+	return func(c *Client) {
+		c.transport.MaxIdleConns = total
+		c.transport.MaxIdleConnsPerHost = perHost
+	}
+}
+
+// WithMaxConnsPerHost - ограничение числа соединений с одним хостом, 0 - без ограничения.
+func WithMaxConnsPerHost(n int) Option {
+	return func(c *Client) {
+		c.transport.MaxConnsPerHost = n
+	}
+}
+
+// WithIdleConnTimeout - через сколько закрывать простаивающее соединение.
+func WithIdleConnTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.transport.IdleConnTimeout = timeout
+	}
+}
+
+// WithKeepAlive - период TCP keep-alive, отрицательное значение отключает его.
+func WithKeepAlive(period time.Duration) Option {
+	return func(c *Client) {
+		c.dialer.KeepAlive = period
+	}
+}
+
+// WithProxy - выбор прокси для запроса, см. http.ProxyURL и http.ProxyFromEnvironment.
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(c *Client) {
+		c.transport.Proxy = proxy
+	}
+}
+
+// WithTLSConfig - настройки TLS, в том числе клиентские сертификаты для mTLS.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Client) {
+		c.transport.TLSClientConfig = cfg
+	}
+}
+
+// WithRoundTripper - собственный транспорт. Опции настройки транспорта при этом не применяются.
+func WithRoundTripper(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.roundTripper = rt
+	}
+}
+
+// WithHeader - заголовок, добавляемый к каждому запросу.
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.headers.Add(key, value)
+	}
+}
+
+// WithUserAgent - User-Agent каждого запроса.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.headers.Set("User-Agent", userAgent)
+	}
+}
+
+// NewClientFromConfig создаёт клиента по настройкам из окружения, opts применяются после них.
+func NewClientFromConfig(clientName string, ownerServiceName string, baseURL string, cfg *config.HTTPClient, opts ...Option) (*Client, error) {
+	cfgOpts := []Option{
+		WithTimeout(cfg.Timeout),
+		WithMaxIdleConns(cfg.MaxIdleConns, cfg.MaxIdleConnsPerHost),
+		WithMaxConnsPerHost(cfg.MaxConnsPerHost),
+		WithIdleConnTimeout(cfg.IdleConnTimeout),
+		WithKeepAlive(cfg.KeepAlive),
+	}
+
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("client config: parse proxy url: %w", err)
+		}
+		cfgOpts = append(cfgOpts, WithProxy(http.ProxyURL(proxyURL)))
+	}
+	if cfg.UserAgent != "" {
+		cfgOpts = append(cfgOpts, WithUserAgent(cfg.UserAgent))
+	}
+
+	tlsConfig, err := tlsFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		cfgOpts = append(cfgOpts, WithTLSConfig(tlsConfig))
+	}
+
+	return NewClient(clientName, ownerServiceName, baseURL, append(cfgOpts, opts...)...), nil
+}
+
+func tlsFromConfig(cfg *config.HTTPClient) (*tls.Config, error) {
+	if cfg.TLSCAFile == "" && cfg.TLSCertFile == "" && !cfg.TLSInsecure {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.TLSInsecure,
+	}
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("client config: read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client config: no certificates in %s", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client config: load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
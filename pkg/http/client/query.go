@@ -0,0 +1,132 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EncodeQuery преобразует параметры запроса в url.Values.
+// Поддерживаются url.Values, map[string]string, map[string][]string, map[string]any
+// и структуры: имя параметра берётся из тега url (или json), omitempty пропускает нулевые значения,
+// "-" исключает поле. Срезы дают повторяющиеся параметры, nil-указатели пропускаются.
+func EncodeQuery(query interface{}) (url.Values, error) {
+	switch q := query.(type) {
+	case url.Values:
+		return q, nil
+	case map[string]string:
+		values := url.Values{}
+		for k, v := range q {
+			values.Set(k, v)
+		}
+		return values, nil
+	case map[string][]string:
+		return url.Values(q), nil
+	}
+
+	v := reflect.ValueOf(query)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return url.Values{}, nil
+		}
+		v = v.Elem()
+	}
+
+	values := url.Values{}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("client: query map key must be string, got %s", v.Type().Key())
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := addQueryValue(values, iter.Key().String(), iter.Value(), false); err != nil {
+				return nil, err
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, omitEmpty := queryFieldName(field)
+			if name == "-" {
+				continue
+			}
+			if err := addQueryValue(values, name, v.Field(i), omitEmpty); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("client: unsupported query type %T", query)
+	}
+	return values, nil
+}
+
+func queryFieldName(field reflect.StructField) (string, bool) {
+	tag, ok := field.Tag.Lookup("url")
+	if !ok {
+		tag = field.Tag.Get("json")
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(opts, "omitempty")
+}
+
+func addQueryValue(values url.Values, name string, v reflect.Value, omitEmpty bool) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if omitEmpty && v.IsZero() {
+		return nil
+	}
+
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			if err := addQueryValue(values, name, v.Index(i), false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	s, err := queryString(v)
+	if err != nil {
+		return fmt.Errorf("client: query parameter %s: %w", name, err)
+	}
+	values.Add(name, s)
+	return nil
+}
+
+func queryString(v reflect.Value) (string, error) {
+	switch val := v.Interface().(type) {
+	case time.Time:
+		return val.Format(time.RFC3339), nil
+	case fmt.Stringer:
+		return val.String(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryBaseDelay = 100 * time.Millisecond
+	defaultRetryMaxDelay  = 5 * time.Second
+
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+var defaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy - политика повтора запросов.
+// Повторяются только идемпотентные методы и запросы с заголовком Idempotency-Key.
+type RetryPolicy struct {
+	MaxAttempts   int           // всего попыток, включая первую
+	BaseDelay     time.Duration // задержка перед второй попыткой, дальше растёт экспоненциально
+	MaxDelay      time.Duration // верхняя граница задержки
+	RetryStatuses []int         // коды ответа, после которых запрос повторяется
+	// MaxRetryAfter - наибольший Retry-After сервиса, который стоит ждать, по умолчанию MaxDelay.
+	// Если сервис просит ждать дольше, повторы прекращаются и возвращается его ответ.
+	MaxRetryAfter time.Duration
+}
+
+// Attempt - сведения об одной попытке запроса, для логирования.
+type Attempt struct {
+	Number     int
+	Method     string
+	URL        string
+	StatusCode int // 0, если ответ не получен
+	Err        error
+	Duration   time.Duration
+	Delay      time.Duration // пауза перед следующей попыткой, 0 для последней
+}
+
+func (p RetryPolicy) withDefaults() *RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 1
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaultRetryBaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaultRetryMaxDelay
+	}
+	if p.RetryStatuses == nil {
+		p.RetryStatuses = defaultRetryStatuses
+	}
+	if p.MaxRetryAfter <= 0 {
+		p.MaxRetryAfter = p.MaxDelay
+	}
+	return &p
+}
+
+// backoff - экспоненциальная задержка с полным джиттером.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return rand.N(d) + 1
+}
+
+func (p *RetryPolicy) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) &&
+			!errors.Is(err, ErrCircuitOpen) && !errors.Is(err, ErrRateLimited) && !errors.Is(err, ErrTooManyInFlight)
+	}
+	return slices.Contains(p.RetryStatuses, resp.StatusCode)
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(idempotencyKeyHeader) != ""
+}
+
+// retryAfter разбирает Retry-After в секундах или в формате HTTP-даты.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if sec, err := strconv.Atoi(v); err == nil && sec >= 0 {
+		return time.Duration(sec) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// doWithRetry выполняет запрос с повторами. Тело запроса перечитывается через GetBody.
+func (c *Client) doWithRetry(req *http.Request, onAttempt func(Attempt)) (*http.Response, error) {
+	policy := c.retry
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	if policy == nil || !isIdempotent(req) || !replayable {
+		policy = &RetryPolicy{MaxAttempts: 1}
+	}
+
+	ctx := req.Context()
+	for n := 1; ; n++ {
+		attemptReq := req
+		if n > 1 {
+			attemptReq = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		start := time.Now()
+		resp, err := c.doHedged(attemptReq)
+		attempt := Attempt{
+			Number:   n,
+			Method:   req.Method,
+			URL:      req.URL.String(),
+			Err:      err,
+			Duration: time.Since(start),
+		}
+		if resp != nil {
+			attempt.StatusCode = resp.StatusCode
+		}
+
+		if n >= policy.MaxAttempts || !policy.retryable(resp, err) {
+			if onAttempt != nil {
+				onAttempt(attempt)
+			}
+			return resp, err
+		}
+
+		attempt.Delay = policy.backoff(n)
+		if d, ok := retryAfter(resp); ok {
+			if d > policy.MaxRetryAfter {
+				// сервис недоступен надолго, ждать нет смысла
+				attempt.Delay = 0
+				if onAttempt != nil {
+					onAttempt(attempt)
+				}
+				return resp, err
+			}
+			attempt.Delay = d
+		}
+		if onAttempt != nil {
+			onAttempt(attempt)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < attempt.Delay {
+			// не успеем дождаться следующей попытки
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(attempt.Delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
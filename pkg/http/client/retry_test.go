@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mlplabs/common-go-pkg/pkg/http/errors/custom"
+)
+
+// flakyServer answers the first failures requests with status and Retry-After, the rest with 200.
+// The bodies of all requests are collected.
+type flakyServer struct {
+	*httptest.Server
+	hits atomic.Int64
+
+	mu     sync.Mutex
+	bodies []string
+}
+
+func newFlakyServer(t *testing.T, failures int64, status int, retryAfter string) *flakyServer {
+	s := &flakyServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+		if s.hits.Add(1) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":{"code":"UNAVAILABLE","message":"try later"}}`))
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestRetryRecovers(t *testing.T) {
+	srv := newFlakyServer(t, 2, http.StatusServiceUnavailable, "")
+	c := NewClient("test", "svc", srv.URL, WithRetry(RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    10 * time.Millisecond,
+	}))
+
+	var attempts []Attempt
+	resp, err := c.Get(context.Background(), "/", &RequestParams{OnAttempt: func(a Attempt) {
+		attempts = append(attempts, a)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if srv.hits.Load() != 3 || len(attempts) != 3 {
+		t.Fatalf("%d requests, %d attempts, want 3 and 3", srv.hits.Load(), len(attempts))
+	}
+	for i, a := range attempts[:2] {
+		if a.Number != i+1 || a.StatusCode != http.StatusServiceUnavailable || a.Delay <= 0 || a.Delay > 10*time.Millisecond {
+			t.Fatalf("attempt %d: %+v", i+1, a)
+		}
+	}
+	if last := attempts[2]; last.StatusCode != http.StatusOK || last.Delay != 0 {
+		t.Fatalf("last attempt: %+v", last)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	srv := newFlakyServer(t, 10, http.StatusBadGateway, "")
+	c := NewClient("test", "svc", srv.URL, WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+
+	_, err := c.Get(context.Background(), "/", nil)
+	var common *custom.CommonError
+	if !errors.As(err, &common) || common.StatusCode() != http.StatusBadGateway {
+		t.Fatalf("want 502, got %v", err)
+	}
+	if srv.hits.Load() != 3 {
+		t.Fatalf("%d requests, want 3", srv.hits.Load())
+	}
+
+	// statuses outside RetryStatuses are not retried
+	srv = newFlakyServer(t, 10, http.StatusInternalServerError, "")
+	c = NewClient("test", "svc", srv.URL, WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+	c.Get(context.Background(), "/", nil)
+	if srv.hits.Load() != 1 {
+		t.Fatalf("500 was retried, %d requests", srv.hits.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	srv := newFlakyServer(t, 1, http.StatusTooManyRequests, "1")
+	c := NewClient("test", "svc", srv.URL, WithRetry(RetryPolicy{
+		MaxAttempts:   2,
+		BaseDelay:     time.Millisecond,
+		MaxRetryAfter: 2 * time.Second,
+	}))
+
+	var attempts []Attempt
+	start := time.Now()
+	resp, err := c.Get(context.Background(), "/", &RequestParams{OnAttempt: func(a Attempt) {
+		attempts = append(attempts, a)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if attempts[0].Delay != time.Second || time.Since(start) < time.Second {
+		t.Fatalf("Retry-After is ignored: delay %s, elapsed %s", attempts[0].Delay, time.Since(start))
+	}
+
+	// a longer Retry-After than MaxRetryAfter returns the response at once
+	srv = newFlakyServer(t, 1, http.StatusServiceUnavailable, "60")
+	c = NewClient("test", "svc", srv.URL, WithRetry(RetryPolicy{MaxAttempts: 2, MaxRetryAfter: time.Second}))
+	_, err = c.Get(context.Background(), "/", nil)
+	var common *custom.CommonError
+	if !errors.As(err, &common) || common.StatusCode() != http.StatusServiceUnavailable {
+		t.Fatalf("want 503, got %v", err)
+	}
+	if srv.hits.Load() != 1 {
+		t.Fatalf("%d requests, want 1", srv.hits.Load())
+	}
+}
+
+func TestRetryStopsBeforeDeadline(t *testing.T) {
+	srv := newFlakyServer(t, 1, http.StatusServiceUnavailable, "1")
+	c := NewClient("test", "svc", srv.URL, WithRetry(RetryPolicy{MaxAttempts: 2, MaxRetryAfter: 2 * time.Second}))
+
+	start := time.Now()
+	_, err := c.Get(context.Background(), "/", &RequestParams{Timeout: 200 * time.Millisecond})
+	var common *custom.CommonError
+	if !errors.As(err, &common) || common.StatusCode() != http.StatusServiceUnavailable {
+		t.Fatalf("want 503, got %v", err)
+	}
+	if srv.hits.Load() != 1 || time.Since(start) > 150*time.Millisecond {
+		t.Fatalf("%d requests in %s, want one without waiting", srv.hits.Load(), time.Since(start))
+	}
+}
+
+func TestRetryIdempotency(t *testing.T) {
+	policy := WithRetry(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+
+	srv := newFlakyServer(t, 1, http.StatusServiceUnavailable, "")
+	c := NewClient("test", "svc", srv.URL, policy)
+	c.Post(context.Background(), "/", &RequestParams{RequestBody: map[string]string{"name": "pen"}})
+	if srv.hits.Load() != 1 {
+		t.Fatalf("POST without Idempotency-Key was retried, %d requests", srv.hits.Load())
+	}
+
+	srv = newFlakyServer(t, 1, http.StatusServiceUnavailable, "")
+	c = NewClient("test", "svc", srv.URL, policy)
+	resp, err := c.Post(context.Background(), "/", &RequestParams{
+		RequestBody:    map[string]string{"name": "pen"},
+		IdempotencyKey: "key",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	// the body is sent again with the retry
+	if len(srv.bodies) != 2 || srv.bodies[0] == "" || srv.bodies[0] != srv.bodies[1] {
+		t.Fatalf("bodies: %q", srv.bodies)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}.withDefaults()
+	for attempt, limit := range map[int]time.Duration{
+		1:  10 * time.Millisecond,
+		2:  20 * time.Millisecond,
+		3:  40 * time.Millisecond,
+		4:  50 * time.Millisecond,
+		70: 50 * time.Millisecond,
+	} {
+		for i := 0; i < 100; i++ {
+			if d := p.backoff(attempt); d <= 0 || d > limit {
+				t.Fatalf("attempt %d: delay %s, want (0, %s]", attempt, d, limit)
+			}
+		}
+	}
+}
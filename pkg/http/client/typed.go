@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/mlplabs/common-go-pkg/pkg/http/response/wrapper"
+)
+
+const (
+	offsetParam = "o"
+	limitParam  = "l"
+
+	// DefaultPageTokenParam - параметр запроса, в котором передаётся next_page_token.
+	DefaultPageTokenParam = "page_token"
+)
+
+// DoData выполняет запрос и возвращает содержимое секции data ответа wrapper.Data.
+func DoData[T any](ctx context.Context, c *Client, method string, path string, params *RequestParams) (T, error) {
+	var out wrapper.DataOf[T]
+	_, err := c.Do(ctx, method, path, withResponseBody(params, &out))
+	return out.Data, err
+}
+
+// GetData - GET-запрос к обработчику wrapper.Data.
+func GetData[T any](ctx context.Context, c *Client, path string, params *RequestParams) (T, error) {
+	return DoData[T](ctx, c, http.MethodGet, path, params)
+}
+
+// GetList - GET-запрос к обработчику wrapper.DataList.
+func GetList[T any](ctx context.Context, c *Client, path string, params *RequestParams) (*wrapper.ListOf[T], error) {
+	out := &wrapper.ListOf[T]{}
+	if _, err := c.Get(ctx, path, withResponseBody(params, out)); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetPage - GET-запрос к обработчику wrapper.DataPages.
+func GetPage[T any](ctx context.Context, c *Client, path string, params *RequestParams) (*wrapper.PaginationOf[T], error) {
+	out := &wrapper.PaginationOf[T]{}
+	if _, err := c.Get(ctx, path, withResponseBody(params, out)); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetScroll - GET-запрос к обработчику wrapper.DataScroll.
+func GetScroll[T any](ctx context.Context, c *Client, path string, params *RequestParams) (*wrapper.ScrollOf[T], error) {
+	out := &wrapper.ScrollOf[T]{}
+	if _, err := c.Get(ctx, path, withResponseBody(params, out)); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IterPages обходит все элементы обработчика wrapper.DataPages, запрашивая страницы по limit
+// через параметры o/l (см. request.GetOffsetLimit). Обход прекращается на первой ошибке.
+func IterPages[T any](ctx context.Context, c *Client, path string, params *RequestParams, limit int) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		query, err := baseQuery(params)
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+
+		offset := 0
+		for {
+			query.Set(offsetParam, strconv.Itoa(offset))
+			query.Set(limitParam, strconv.Itoa(limit))
+			page, err := GetPage[T](ctx, c, path, withQuery(params, query))
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page.Data {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			offset += len(page.Data)
+			if len(page.Data) == 0 || offset >= page.Count {
+				return
+			}
+		}
+	}
+}
+
+// IterScroll обходит все элементы обработчика wrapper.DataScroll, передавая next_page_token
+// в параметре tokenParam (по умолчанию DefaultPageTokenParam). Обход прекращается на первой ошибке.
+func IterScroll[T any](ctx context.Context, c *Client, path string, params *RequestParams, tokenParam string) iter.Seq2[T, error] {
+	if tokenParam == "" {
+		tokenParam = DefaultPageTokenParam
+	}
+	return func(yield func(T, error) bool) {
+		query, err := baseQuery(params)
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+
+		for {
+			page, err := GetScroll[T](ctx, c, path, withQuery(params, query))
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page.Data {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if page.Meta == nil || page.Meta.NextPageToken == nil || *page.Meta.NextPageToken == "" {
+				return
+			}
+			query.Set(tokenParam, *page.Meta.NextPageToken)
+		}
+	}
+}
+
+// withResponseBody возвращает копию params с ResponseBody, не изменяя переданные параметры.
+func withResponseBody(params *RequestParams, out interface{}) *RequestParams {
+	p := RequestParams{}
+	if params != nil {
+		p = *params
+	}
+	p.ResponseBody = out
+	return &p
+}
+
+func withQuery(params *RequestParams, query url.Values) *RequestParams {
+	p := RequestParams{}
+	if params != nil {
+		p = *params
+	}
+	p.Query = query
+	return &p
+}
+
+func baseQuery(params *RequestParams) (url.Values, error) {
+	if params == nil || params.Query == nil {
+		return url.Values{}, nil
+	}
+	query, err := EncodeQuery(params.Query)
+	if err != nil {
+		return nil, err
+	}
+	// копия, чтобы не менять url.Values вызывающего
+	res := make(url.Values, len(query))
+	for k, v := range query {
+		res[k] = append([]string(nil), v...)
+	}
+	return res, nil
+}
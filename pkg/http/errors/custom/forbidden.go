@@ -0,0 +1,28 @@
+package custom
+
+type Forbidden struct {
+	err error
+}
+
+func (*Forbidden) StatusCode() int {
+	return 403
+}
+
+func (*Forbidden) ErrorCode() string {
+	return "FORBIDDEN"
+}
+
+func (e *Forbidden) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return "Недостаточно прав"
+}
+
+func (e *Forbidden) Unwrap() error {
+	return e.err
+}
+
+func NewForbidden(err error) *Forbidden {
+	return &Forbidden{err: err}
+}
@@ -0,0 +1,24 @@
+package custom
+
+type PreconditionFailed struct {
+	err error
+}
+
+func (*PreconditionFailed) StatusCode() int {
+	return 412
+}
+
+func (*PreconditionFailed) ErrorCode() string {
+	return "PRECONDITION_FAILED"
+}
+
+func (e *PreconditionFailed) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return "Объект был изменён"
+}
+
+func NewPreconditionFailed(err error) *PreconditionFailed {
+	return &PreconditionFailed{err: err}
+}
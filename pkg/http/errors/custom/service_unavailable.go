@@ -0,0 +1,34 @@
+package custom
+
+type ServiceUnavailable struct {
+	err     error
+	service string
+}
+
+func (e *ServiceUnavailable) LogError() error {
+	return e.err
+}
+
+func (*ServiceUnavailable) StatusCode() int {
+	return 503
+}
+
+func (*ServiceUnavailable) ErrorCode() string {
+	return "SERVICE_UNAVAILABLE"
+}
+
+func (e *ServiceUnavailable) Error() string {
+	return "Сервис временно недоступен"
+}
+
+func (e *ServiceUnavailable) Service() string {
+	return e.service
+}
+
+func (e *ServiceUnavailable) Unwrap() error {
+	return e.err
+}
+
+func NewServiceUnavailable(err error, service string) *ServiceUnavailable {
+	return &ServiceUnavailable{err: err, service: service}
+}
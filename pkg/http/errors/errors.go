@@ -57,7 +57,9 @@ func parseError(err error) (CommonError, string, error) {
 	return e, serviceName, logMsg
 }
 
-func SetError(w http.ResponseWriter, r *http.Request, err error) {
+// NewResponse converts err into the standard error envelope and logs the
+// underlying cause. It returns the HTTP status the envelope should be sent with.
+func NewResponse(r *http.Request, err error) (int, ResponseError) {
 	clientServiceName := "this service"
 	if r != nil {
 		clientServiceName = r.Header.Get("X-Service-Name")
@@ -66,8 +68,7 @@ func SetError(w http.ResponseWriter, r *http.Request, err error) {
 	if errLog != nil {
 		log.Printf("ERROR: client: %s, err: %v", clientServiceName, errLog)
 	}
-	w.WriteHeader(commonErr.StatusCode())
-	data := ResponseError{
+	return commonErr.StatusCode(), ResponseError{
 		Error: Response{
 			Code:    commonErr.ErrorCode(),
 			Message: commonErr.Error(),
@@ -75,6 +76,11 @@ func SetError(w http.ResponseWriter, r *http.Request, err error) {
 			Service: serviceName,
 		},
 	}
+}
+
+func SetError(w http.ResponseWriter, r *http.Request, err error) {
+	statusCode, data := NewResponse(r, err)
+	w.WriteHeader(statusCode)
 	body, err := json.Marshal(data)
 	if err != nil {
 		SetError(w, nil, err)
@@ -0,0 +1,120 @@
+package jwtutils
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	httpErrors "github.com/mlplabs/common-go-pkg/pkg/http/errors"
+	"github.com/mlplabs/common-go-pkg/pkg/http/errors/custom"
+)
+
+// Requirement checks the claims validated by the TokenValidate middleware.
+type Requirement func(r *http.Request) bool
+
+// Role requires the token to have role.
+func Role(role string) Requirement {
+	return func(r *http.Request) bool {
+		return HasRole(r.Context(), role)
+	}
+}
+
+// Scope requires the token to have scope.
+func Scope(scope string) Requirement {
+	return func(r *http.Request) bool {
+		return HasScope(r.Context(), scope)
+	}
+}
+
+// Any is met when at least one of reqs is met.
+func Any(reqs ...Requirement) Requirement {
+	return func(r *http.Request) bool {
+		for _, req := range reqs {
+			if req(r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// All is met when every one of reqs is met.
+func All(reqs ...Requirement) Requirement {
+	return func(r *http.Request) bool {
+		for _, req := range reqs {
+			if !req(r) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Require is a middleware rejecting requests that do not meet req with 403 Forbidden.
+// It must be mounted after TokenValidate; requests without validated claims get 401.
+//
+//	r.With(jwtutils.Require(jwtutils.Any(jwtutils.Role("admin"), jwtutils.Scope("items:write")))).Post("/items", h)
+func Require(req Requirement) func(http.Handler) http.Handler {
+	return RequirePolicy(func(r *http.Request) error {
+		if !req(r) {
+			return custom.NewForbidden(nil)
+		}
+		return nil
+	})
+}
+
+// RequireRoles requires the token to have every one of roles.
+func RequireRoles(roles ...string) func(http.Handler) http.Handler {
+	reqs := make([]Requirement, len(roles))
+	for i, role := range roles {
+		reqs[i] = Role(role)
+	}
+	return Require(All(reqs...))
+}
+
+// RequireScopes requires the token to have every one of scopes.
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	reqs := make([]Requirement, len(scopes))
+	for i, scope := range scopes {
+		reqs[i] = Scope(scope)
+	}
+	return Require(All(reqs...))
+}
+
+// RequireAny requires at least one of reqs to be met.
+func RequireAny(reqs ...Requirement) func(http.Handler) http.Handler {
+	return Require(Any(reqs...))
+}
+
+// RequireAll requires every one of reqs to be met.
+func RequireAll(reqs ...Requirement) func(http.Handler) http.Handler {
+	return Require(All(reqs...))
+}
+
+// Policy is a resource-level check, e.g. that the subject owns the requested object.
+// It returns nil to allow the request. An error implementing errors.CommonError is rendered
+// as is, any other error is logged and rendered as 403 Forbidden with a generic message.
+type Policy func(r *http.Request) error
+
+// RequirePolicy is a middleware rejecting requests denied by policy.
+func RequirePolicy(policy Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := ClaimsFromContext(r.Context()); !ok {
+				httpErrors.SetError(w, r, newTokenError(ErrTokenMissing, nil))
+				return
+			}
+			if err := policy(r); err != nil {
+				var commonErr httpErrors.CommonError
+				if !errors.As(err, &commonErr) {
+					// the cause may hold internal details, so it is logged and not sent to the client
+					log.Printf("ERROR: policy denied %s %s: %v", r.Method, r.URL.Path, err)
+					err = custom.NewForbidden(nil)
+				}
+				httpErrors.SetError(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package jwtutils
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+
+	"github.com/mlplabs/common-go-pkg/pkg/http/errors/custom"
+)
+
+// authorize passes a request with claims (none if nil) through mw and returns the recorded response.
+func authorize(mw func(http.Handler) http.Handler, claims jwt.MapClaims) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	if claims != nil {
+		req = req.WithContext(ContextWithClaims(req.Context(), claims))
+	}
+	mw(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequire(t *testing.T) {
+	claims := jwt.MapClaims{"roles": []interface{}{"editor"}, "scope": "items:read items:write"}
+	for _, tc := range []struct {
+		name   string
+		mw     func(http.Handler) http.Handler
+		claims jwt.MapClaims
+		status int
+	}{
+		{"role", RequireRoles("editor"), claims, http.StatusOK},
+		{"missing role", RequireRoles("editor", "admin"), claims, http.StatusForbidden},
+		{"scopes", RequireScopes("items:read", "items:write"), claims, http.StatusOK},
+		{"missing scope", RequireScopes("items:delete"), claims, http.StatusForbidden},
+		{"any", RequireAny(Role("admin"), Scope("items:write")), claims, http.StatusOK},
+		{"none of any", RequireAny(Role("admin"), Scope("items:delete")), claims, http.StatusForbidden},
+		{"all", RequireAll(Role("editor"), Scope("items:read")), claims, http.StatusOK},
+		{"comma separated roles", RequireRoles("admin"), jwt.MapClaims{"roles": "editor,admin"}, http.StatusOK},
+		{"no claims", RequireRoles("editor"), nil, http.StatusUnauthorized},
+	} {
+		if rec := authorize(tc.mw, tc.claims); rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.status)
+		}
+	}
+}
+
+func TestRequirePolicy(t *testing.T) {
+	var logged bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&logged)
+
+	owner := func(r *http.Request) error {
+		if Subject(r.Context()) != "owner" {
+			return errors.New("items.owner_id = 42, subject is not the owner")
+		}
+		return nil
+	}
+	mw := RequirePolicy(owner)
+
+	if rec := authorize(mw, nil); rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "UNAUTHORIZED") {
+		t.Fatalf("without claims: status %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := authorize(mw, jwt.MapClaims{"sub": "owner"}); rec.Code != http.StatusOK {
+		t.Fatalf("owner: status %d", rec.Code)
+	}
+
+	// the cause is logged, the client gets a generic forbidden message
+	rec := authorize(mw, jwt.MapClaims{"sub": "guest"})
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "FORBIDDEN") {
+		t.Fatalf("guest: status %d, body %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "owner_id") {
+		t.Fatalf("policy error details are sent to the client: %s", rec.Body)
+	}
+	if !strings.Contains(logged.String(), "owner_id") {
+		t.Fatalf("policy error is not logged: %q", logged.String())
+	}
+
+	// errors with a status are rendered as is
+	rec = authorize(RequirePolicy(func(*http.Request) error { return custom.NewErrorNoRows(nil) }), jwt.MapClaims{"sub": "guest"})
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "OBJECT_DOES_NOT_EXIST") {
+		t.Fatalf("not found policy: status %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestRequireAfterTokenValidate(t *testing.T) {
+	h := TokenValidate("secret")(RequireRoles("admin")(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})))
+	for _, tc := range []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"admin", sign(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"roles": "admin"}), http.StatusOK},
+		{"user", sign(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"roles": "user"}), http.StatusForbidden},
+		{"no token", "", http.StatusUnauthorized},
+	} {
+		status, _ := serve(t, func(http.Handler) http.Handler { return h }, tc.token)
+		if status != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, status, tc.status)
+		}
+	}
+}
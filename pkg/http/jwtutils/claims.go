@@ -0,0 +1,102 @@
+package jwtutils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// ErrNoClaims means the context carries no validated token claims.
+var ErrNoClaims = errors.New("No token claims in context")
+
+type claimsKey struct{}
+
+// ContextWithClaims stores validated token claims, done by the TokenValidate middleware.
+func ContextWithClaims(ctx context.Context, claims jwt.MapClaims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the token validated by the TokenValidate middleware.
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(jwt.MapClaims)
+	return claims, ok
+}
+
+// ClaimsAs decodes the validated claims into a custom struct, matching fields by their json tags.
+//
+//	type UserClaims struct {
+//		UserID int64  `json:"user_id"`
+//		Email  string `json:"email"`
+//	}
+//	claims, err := jwtutils.ClaimsAs[UserClaims](r.Context())
+func ClaimsAs[T any](ctx context.Context) (T, error) {
+	var out T
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return out, ErrNoClaims
+	}
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return out, err
+	}
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
+
+// Subject returns the sub claim.
+func Subject(ctx context.Context) string {
+	claims, _ := ClaimsFromContext(ctx)
+	sub, _ := claims["sub"].(string)
+	return sub
+}
+
+// Roles returns the roles claim, given either as an array or as a space or comma separated string.
+// A single role claim is accepted too.
+func Roles(ctx context.Context) []string {
+	claims, _ := ClaimsFromContext(ctx)
+	if roles := stringList(claims["roles"]); roles != nil {
+		return roles
+	}
+	return stringList(claims["role"])
+}
+
+// Scopes returns the OAuth2 scope claim (space separated) or the scp claim (array).
+func Scopes(ctx context.Context) []string {
+	claims, _ := ClaimsFromContext(ctx)
+	if scopes := stringList(claims["scope"]); scopes != nil {
+		return scopes
+	}
+	return stringList(claims["scp"])
+}
+
+// HasRole reports whether the token has role.
+func HasRole(ctx context.Context, role string) bool {
+	return slices.Contains(Roles(ctx), role)
+}
+
+// HasScope reports whether the token has scope.
+func HasScope(ctx context.Context, scope string) bool {
+	return slices.Contains(Scopes(ctx), scope)
+}
+
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.FieldsFunc(v, func(r rune) bool { return r == ' ' || r == ',' })
+	case []string:
+		return v
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
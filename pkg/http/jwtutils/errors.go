@@ -0,0 +1,90 @@
+package jwtutils
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Token validation failures, check them with errors.Is.
+var (
+	ErrTokenMissing      = errors.New("token is missing")
+	ErrTokenMalformed    = errors.New("token is malformed")
+	ErrTokenSignature    = errors.New("token signature is invalid")
+	ErrTokenUnknownKey   = errors.New("token signing key is unknown")
+	ErrTokenExpired      = errors.New("token is expired")
+	ErrTokenNotValidYet  = errors.New("token is not valid yet")
+	ErrTokenTooOld       = errors.New("token is too old")
+	ErrTokenIssuer       = errors.New("token issuer is not allowed")
+	ErrTokenAudience     = errors.New("token audience is not allowed")
+	ErrTokenMissingClaim = errors.New("token claim is missing")
+	ErrTokenType         = errors.New("token type is not allowed")
+	ErrTokenRevoked      = errors.New("token is revoked")
+	ErrTokenReused       = errors.New("refresh token is reused")
+)
+
+var tokenErrorCodes = map[error]struct {
+	code    string
+	message string
+}{
+	ErrTokenMissing:      {"UNAUTHORIZED", "требуется авторизация"},
+	ErrTokenMalformed:    {"TOKEN_MALFORMED", "невалидный токен"},
+	ErrTokenSignature:    {"TOKEN_INVALID_SIGNATURE", "невалидная подпись токена"},
+	ErrTokenUnknownKey:   {"TOKEN_UNKNOWN_KEY", "токен подписан неизвестным ключом"},
+	ErrTokenExpired:      {"TOKEN_EXPIRED", "срок действия токена истёк"},
+	ErrTokenNotValidYet:  {"TOKEN_NOT_VALID_YET", "токен ещё не действителен"},
+	ErrTokenTooOld:       {"TOKEN_TOO_OLD", "токен выпущен слишком давно"},
+	ErrTokenIssuer:       {"TOKEN_INVALID_ISSUER", "токен выпущен недопустимым издателем"},
+	ErrTokenAudience:     {"TOKEN_INVALID_AUDIENCE", "токен выпущен для другого получателя"},
+	ErrTokenMissingClaim: {"TOKEN_MISSING_CLAIM", "в токене нет обязательных данных"},
+	ErrTokenType:         {"TOKEN_INVALID_TYPE", "недопустимый тип токена"},
+	ErrTokenRevoked:      {"TOKEN_REVOKED", "токен отозван"},
+	ErrTokenReused:       {"TOKEN_REUSED", "токен уже использован"},
+}
+
+// TokenError is a token validation failure. It is rendered by errors.SetError
+// as 401 with an error code distinct for each kind, e.g. TOKEN_EXPIRED.
+type TokenError struct {
+	kind error
+	err  error
+}
+
+func newTokenError(kind error, err error) *TokenError {
+	return &TokenError{kind: kind, err: err}
+}
+
+func (*TokenError) StatusCode() int {
+	return http.StatusUnauthorized
+}
+
+func (e *TokenError) ErrorCode() string {
+	return tokenErrorCodes[e.kind].code
+}
+
+func (e *TokenError) Error() string {
+	return tokenErrorCodes[e.kind].message
+}
+
+func (e *TokenError) Unwrap() []error {
+	if e.err == nil {
+		return []error{e.kind}
+	}
+	return []error{e.kind, e.err}
+}
+
+// parseError converts a jwt parser error into a TokenError.
+func parseError(err error) *TokenError {
+	var ve *jwt.ValidationError
+	if !errors.As(err, &ve) {
+		return newTokenError(ErrTokenSignature, err)
+	}
+	switch {
+	case ve.Errors&jwt.ValidationErrorMalformed != 0:
+		return newTokenError(ErrTokenMalformed, err)
+	// the key lookup failed, e.g. a kid missing from the JWKS: the signature was not checked at all
+	case errors.Is(ve.Inner, ErrUnknownKeyID) || errors.Is(ve.Inner, ErrKeyAlgorithm):
+		return newTokenError(ErrTokenUnknownKey, ve.Inner)
+	}
+	return newTokenError(ErrTokenSignature, err)
+}
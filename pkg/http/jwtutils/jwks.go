@@ -0,0 +1,301 @@
+package jwtutils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJWKSRefreshInterval    = time.Hour
+	defaultJWKSMinRefetchInterval = time.Minute
+	defaultJWKSFetchTimeout       = 10 * time.Second
+)
+
+var (
+	ErrUnknownKeyID = errors.New("Unknown key id")
+	ErrKeyAlgorithm = errors.New("Key is not published for the signing algorithm")
+)
+
+// JWKSConfig configures a JWKS key set. Either URL or File is required.
+type JWKSConfig struct {
+	URL        string
+	File       string
+	HTTPClient *http.Client // http.DefaultClient by default
+
+	// RefreshInterval is how often keys are reloaded in the background, 1 hour by default.
+	RefreshInterval time.Duration
+	// MinRefetchInterval limits reloads caused by tokens with an unknown kid, 1 minute by default.
+	MinRefetchInterval time.Duration
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwksKey struct {
+	key interface{}
+	alg string
+}
+
+// JWKS is a KeySet loaded from a JSON Web Key Set. Keys are cached and reloaded periodically;
+// a token with an unknown kid triggers a reload at most once per MinRefetchInterval,
+// so rotated keys are picked up without waiting for the next refresh.
+type JWKS struct {
+	cfg  JWKSConfig
+	stop context.CancelFunc
+
+	mu   sync.RWMutex
+	keys map[string]jwksKey
+
+	fetchMu   sync.Mutex
+	lastFetch time.Time
+}
+
+// NewJWKS loads the key set and refreshes it in the background until ctx is done or Close is called.
+func NewJWKS(ctx context.Context, cfg JWKSConfig) (*JWKS, error) {
+	if cfg.URL == "" && cfg.File == "" {
+		return nil, fmt.Errorf("jwks: url or file is required")
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = defaultJWKSRefreshInterval
+	}
+	if cfg.MinRefetchInterval <= 0 {
+		cfg.MinRefetchInterval = defaultJWKSMinRefetchInterval
+	}
+
+	s := &JWKS{cfg: cfg}
+	s.lastFetch = time.Now()
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	ctx, s.stop = context.WithCancel(ctx)
+	go s.refreshLoop(ctx)
+	return s, nil
+}
+
+// Close stops the background refresh.
+func (s *JWKS) Close() {
+	s.stop()
+}
+
+// Key returns the key with id kid published for alg. An empty kid is accepted when the set has a single key.
+func (s *JWKS) Key(kid string, alg string) (interface{}, error) {
+	k, ok := s.lookup(kid)
+	if !ok && s.refetch() {
+		k, ok = s.lookup(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyID, kid)
+	}
+	if k.alg != "" && k.alg != alg {
+		return nil, fmt.Errorf("%w: %s", ErrKeyAlgorithm, alg)
+	}
+	return k.key, nil
+}
+
+// Refresh reloads the key set.
+func (s *JWKS) Refresh(ctx context.Context) error {
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+
+	s.lastFetch = time.Now()
+	return s.load(ctx)
+}
+
+func (s *JWKS) lookup(kid string) (jwksKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if kid == "" && len(s.keys) == 1 {
+		for _, k := range s.keys {
+			return k, true
+		}
+	}
+	k, ok := s.keys[kid]
+	return k, ok
+}
+
+// refetch reloads keys unless they were loaded less than MinRefetchInterval ago.
+func (s *JWKS) refetch() bool {
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+
+	if time.Since(s.lastFetch) < s.cfg.MinRefetchInterval {
+		return false
+	}
+	s.lastFetch = time.Now()
+	if err := s.load(context.Background()); err != nil {
+		log.Printf("ERROR: %v", err)
+		return false
+	}
+	return true
+}
+
+func (s *JWKS) refreshLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// on error the previous keys are kept
+			if err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("ERROR: %v", err)
+			}
+		}
+	}
+}
+
+func (s *JWKS) load(ctx context.Context) error {
+	data, err := s.read(ctx)
+	if err != nil {
+		return fmt.Errorf("jwks: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *JWKS) read(ctx context.Context) ([]byte, error) {
+	if s.cfg.File != "" {
+		return os.ReadFile(s.cfg.File)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultJWKSFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d from %s", resp.StatusCode, s.cfg.URL)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// parseJWKS parses a JSON Web Key Set into keys by kid. Keys not meant for signatures
+// and key types other than RSA, EC and Ed25519 are skipped.
+func parseJWKS(data []byte) (map[string]jwksKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	keys := make(map[string]jwksKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if errors.Is(err, ErrUnsupportedKey) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwks: key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = jwksKey{key: key, alg: k.Alg}
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("%w: curve %s", ErrUnsupportedKey, k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("%w: curve %s", ErrUnsupportedKey, k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key size %d", len(x))
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("%w: kty %s", ErrUnsupportedKey, k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,208 @@
+package jwtutils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// jwksServer publishes a replaceable set of RSA keys and counts fetches.
+type jwksServer struct {
+	*httptest.Server
+	fetches atomic.Int64
+
+	mu   sync.Mutex
+	keys []jwk
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	s := &jwksServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string][]jwk{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) publish(keys map[string]*rsa.PrivateKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = s.keys[:0]
+	for kid, k := range keys {
+		s.keys = append(s.keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		})
+	}
+}
+
+func generateRSA(t *testing.T) *rsa.PrivateKey {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func signRS256(t *testing.T, k *rsa.PrivateKey, kid string) string {
+	s, err := NewSigner(k, "RS256", kid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := s.Sign(jwt.MapClaims{"sub": "user", "exp": time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestJWKSValidatesPublishedKey(t *testing.T) {
+	k1 := generateRSA(t)
+	srv := newJWKSServer(t)
+	srv.publish(map[string]*rsa.PrivateKey{"k1": k1})
+
+	ks, err := NewJWKS(context.Background(), JWKSConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ks.Close()
+	v, err := NewKeySetValidator(ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.Parse(signRS256(t, k1, "k1")); err != nil {
+		t.Fatal(err)
+	}
+	// the same key under another kid is not trusted
+	_, err = v.Parse(signRS256(t, k1, "other"))
+	var tokenErr *TokenError
+	if !errors.As(err, &tokenErr) || tokenErr.ErrorCode() != "TOKEN_UNKNOWN_KEY" || !errors.Is(err, ErrUnknownKeyID) {
+		t.Fatalf("want TOKEN_UNKNOWN_KEY, got %v", err)
+	}
+	if _, err := ks.Key("k1", "RS512"); !errors.Is(err, ErrKeyAlgorithm) {
+		t.Fatalf("want ErrKeyAlgorithm, got %v", err)
+	}
+}
+
+func TestJWKSRefetchesUnknownKey(t *testing.T) {
+	k1, k2 := generateRSA(t), generateRSA(t)
+	srv := newJWKSServer(t)
+	srv.publish(map[string]*rsa.PrivateKey{"k1": k1})
+
+	ks, err := NewJWKS(context.Background(), JWKSConfig{
+		URL:                srv.URL,
+		RefreshInterval:    time.Hour,
+		MinRefetchInterval: 200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ks.Close()
+	v, err := NewKeySetValidator(ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the issuer starts signing with a new key
+	srv.publish(map[string]*rsa.PrivateKey{"k1": k1, "k2": k2})
+	token := signRS256(t, k2, "k2")
+
+	// right after the initial load a refetch is not allowed yet
+	if _, err := ks.Key("k2", "RS256"); !errors.Is(err, ErrUnknownKeyID) {
+		t.Fatalf("want ErrUnknownKeyID, got %v", err)
+	}
+	if n := srv.fetches.Load(); n != 1 {
+		t.Fatalf("%d fetches, want 1", n)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if _, err := v.Parse(token); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.fetches.Load(); n != 2 {
+		t.Fatalf("%d fetches, want 2", n)
+	}
+
+	// unknown kids do not cause a fetch per token
+	for i := 0; i < 10; i++ {
+		ks.Key("missing", "RS256")
+	}
+	if n := srv.fetches.Load(); n != 2 {
+		t.Fatalf("%d fetches after unknown kids, want 2", n)
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+	k1, k2 := generateRSA(t), generateRSA(t)
+	srv := newJWKSServer(t)
+	srv.publish(map[string]*rsa.PrivateKey{"k1": k1})
+
+	ks, err := NewJWKS(context.Background(), JWKSConfig{
+		URL:                srv.URL,
+		RefreshInterval:    10 * time.Millisecond,
+		MinRefetchInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ks.Close()
+
+	// k1 is retired, k2 takes its place and is picked up by the background refresh
+	srv.publish(map[string]*rsa.PrivateKey{"k2": k2})
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, errOld := ks.Key("k1", "RS256")
+		_, errNew := ks.Key("k2", "RS256")
+		if errOld != nil && errNew == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("keys were not rotated: k1 %v, k2 %v", errOld, errNew)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestJWKSStopsWithContext(t *testing.T) {
+	srv := newJWKSServer(t)
+	srv.publish(map[string]*rsa.PrivateKey{"k1": generateRSA(t)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ks, err := NewJWKS(ctx, JWKSConfig{URL: srv.URL, RefreshInterval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ks.Close()
+
+	time.Sleep(30 * time.Millisecond)
+	if srv.fetches.Load() < 2 {
+		t.Fatalf("keys were not refreshed, %d fetches", srv.fetches.Load())
+	}
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	fetches := srv.fetches.Load()
+	time.Sleep(30 * time.Millisecond)
+	if n := srv.fetches.Load(); n != fetches {
+		t.Fatalf("refresh continued after the context was cancelled: %d fetches, want %d", n, fetches)
+	}
+}
@@ -0,0 +1,133 @@
+package jwtutils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrUnsupportedKey      = errors.New("Key type is not supported")
+	ErrAlgorithmNotAllowed = errors.New("Signing algorithm is not allowed for the key")
+	ErrNoAlgorithmsAllowed = errors.New("No signing algorithms allowed")
+	ErrUnknownPEMBlock     = errors.New("Unknown PEM block type")
+)
+
+// PublicKeyFromPEM parses a PEM encoded RSA, ECDSA or Ed25519 public key.
+// PKIX, PKCS1 and X.509 certificate blocks are accepted.
+func PublicKeyFromPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrKeyMustBePEMEncoded
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownPEMBlock, block.Type)
+}
+
+// PublicKeyFromBase64 parses a base64 encoded PEM public key, as stored in config.Auth.PublicKeyBase64.
+func PublicKeyFromBase64(s string) (crypto.PublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("decode public key: %w", err)
+	}
+	return PublicKeyFromPEM(data)
+}
+
+// PrivateKeyFromPEM parses a PEM encoded RSA, ECDSA or Ed25519 private key.
+func PrivateKeyFromPEM(data []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrKeyMustBePEMEncoded
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownPEMBlock, block.Type)
+}
+
+// PrivateKeyFromBase64 parses a base64 encoded PEM private key.
+func PrivateKeyFromBase64(s string) (crypto.PrivateKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("decode private key: %w", err)
+	}
+	return PrivateKeyFromPEM(data)
+}
+
+// AlgorithmsForKey returns the signing algorithms that may be used with key.
+// []byte is an HMAC secret; RSA, ECDSA and Ed25519 keys may be public or private.
+func AlgorithmsForKey(key interface{}) []string {
+	switch k := key.(type) {
+	case []byte:
+		return []string{"HS256", "HS384", "HS512"}
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+	case *ecdsa.PublicKey:
+		return ecdsaAlgorithms(k)
+	case *ecdsa.PrivateKey:
+		return ecdsaAlgorithms(&k.PublicKey)
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		return []string{"EdDSA"}
+	}
+	return nil
+}
+
+func asymmetricAlgorithms() []string {
+	return []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+}
+
+func ecdsaAlgorithms(k *ecdsa.PublicKey) []string {
+	switch k.Curve.Params().BitSize {
+	case 256:
+		return []string{"ES256"}
+	case 384:
+		return []string{"ES384"}
+	case 521:
+		return []string{"ES512"}
+	}
+	return nil
+}
+
+// checkAlgorithms ensures every algorithm in algorithms can be used with key.
+// Mixing HMAC with public keys is what alg-confusion attacks rely on, so it is rejected here.
+func checkAlgorithms(key interface{}, algorithms []string) error {
+	allowed := AlgorithmsForKey(key)
+	if allowed == nil {
+		return fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+	if len(algorithms) == 0 {
+		return ErrNoAlgorithmsAllowed
+	}
+	for _, alg := range algorithms {
+		if !slices.Contains(allowed, alg) {
+			return fmt.Errorf("%w: %s for %T", ErrAlgorithmNotAllowed, alg, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package jwtutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// ValidationOptions are the claim checks applied after the signature is verified.
+// exp, nbf and iat are checked whenever present; the rest only when set.
+type ValidationOptions struct {
+	Issuers        []string      // allowed iss values
+	Audiences      []string      // aud must contain at least one of them
+	Leeway         time.Duration // allowed clock skew for exp, nbf and iat
+	MaxAge         time.Duration // maximum time since iat, iat becomes required
+	RequiredClaims []string      // claims that must be present
+	TokenType      string        // expected typ claim or header, e.g. "access"; refresh tokens are rejected unless set
+}
+
+// WithValidation sets the claim checks of a Validator.
+func WithValidation(opts ValidationOptions) Option {
+	return func(v *Validator) {
+		v.options = opts
+	}
+}
+
+func (o ValidationOptions) validate(token *jwt.Token, claims jwt.MapClaims, now time.Time) error {
+	typ, _ := claims["typ"].(string)
+	if typ == "" {
+		typ, _ = token.Header["typ"].(string)
+	}
+	// a refresh token is accepted only where it is asked for, so it never passes as an access token
+	if o.TokenType != "" && typ != o.TokenType || o.TokenType == "" && typ == TokenTypeRefresh {
+		return newTokenError(ErrTokenType, fmt.Errorf("typ %q", typ))
+	}
+	for _, name := range o.RequiredClaims {
+		if _, ok := claims[name]; !ok {
+			return newTokenError(ErrTokenMissingClaim, fmt.Errorf("claim %s", name))
+		}
+	}
+
+	exp, ok, err := timeClaim(claims, "exp")
+	if err != nil {
+		return newTokenError(ErrTokenMalformed, err)
+	}
+	if ok && !now.Before(exp.Add(o.Leeway)) {
+		return newTokenError(ErrTokenExpired, nil)
+	}
+	nbf, ok, err := timeClaim(claims, "nbf")
+	if err != nil {
+		return newTokenError(ErrTokenMalformed, err)
+	}
+	if ok && now.Add(o.Leeway).Before(nbf) {
+		return newTokenError(ErrTokenNotValidYet, nil)
+	}
+	iat, ok, err := timeClaim(claims, "iat")
+	if err != nil {
+		return newTokenError(ErrTokenMalformed, err)
+	}
+	if ok && now.Add(o.Leeway).Before(iat) {
+		return newTokenError(ErrTokenNotValidYet, fmt.Errorf("issued in the future"))
+	}
+	if o.MaxAge > 0 {
+		if !ok {
+			return newTokenError(ErrTokenMissingClaim, fmt.Errorf("claim iat"))
+		}
+		if now.Sub(iat) > o.MaxAge+o.Leeway {
+			return newTokenError(ErrTokenTooOld, nil)
+		}
+	}
+
+	if len(o.Issuers) > 0 {
+		iss, _ := claims["iss"].(string)
+		if !slices.Contains(o.Issuers, iss) {
+			return newTokenError(ErrTokenIssuer, fmt.Errorf("iss %q", iss))
+		}
+	}
+	if len(o.Audiences) > 0 {
+		aud := stringList(claims["aud"])
+		if !slices.ContainsFunc(aud, func(a string) bool { return slices.Contains(o.Audiences, a) }) {
+			return newTokenError(ErrTokenAudience, fmt.Errorf("aud %q", aud))
+		}
+	}
+	return nil
+}
+
+// timeClaim reads a NumericDate claim.
+func timeClaim(claims jwt.MapClaims, name string) (time.Time, bool, error) {
+	var sec float64
+	switch v := claims[name].(type) {
+	case nil:
+		return time.Time{}, false, nil
+	case float64:
+		sec = v
+	case int64:
+		sec = float64(v)
+	case int:
+		sec = float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("claim %s: %w", name, err)
+		}
+		sec = f
+	default:
+		return time.Time{}, false, fmt.Errorf("claim %s is not a number", name)
+	}
+	return time.Unix(0, int64(sec*float64(time.Second))), true, nil
+}
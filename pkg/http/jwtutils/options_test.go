@@ -0,0 +1,167 @@
+package jwtutils
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func TestTokenErrorCodes(t *testing.T) {
+	for kind, code := range map[error]string{
+		ErrTokenMissing:      "UNAUTHORIZED",
+		ErrTokenMalformed:    "TOKEN_MALFORMED",
+		ErrTokenSignature:    "TOKEN_INVALID_SIGNATURE",
+		ErrTokenUnknownKey:   "TOKEN_UNKNOWN_KEY",
+		ErrTokenExpired:      "TOKEN_EXPIRED",
+		ErrTokenNotValidYet:  "TOKEN_NOT_VALID_YET",
+		ErrTokenTooOld:       "TOKEN_TOO_OLD",
+		ErrTokenIssuer:       "TOKEN_INVALID_ISSUER",
+		ErrTokenAudience:     "TOKEN_INVALID_AUDIENCE",
+		ErrTokenMissingClaim: "TOKEN_MISSING_CLAIM",
+		ErrTokenType:         "TOKEN_INVALID_TYPE",
+		ErrTokenRevoked:      "TOKEN_REVOKED",
+		ErrTokenReused:       "TOKEN_REUSED",
+	} {
+		err := newTokenError(kind, errors.New("cause"))
+		if err.ErrorCode() != code || err.StatusCode() != http.StatusUnauthorized || err.Error() == "" || !errors.Is(err, kind) {
+			t.Errorf("%v: code %s, status %d, message %q", kind, err.ErrorCode(), err.StatusCode(), err.Error())
+		}
+	}
+}
+
+// errKeySet fails every key lookup with err.
+type errKeySet struct{ err error }
+
+func (k errKeySet) Key(string, string) (interface{}, error) {
+	return nil, k.err
+}
+
+func TestValidationErrorCodes(t *testing.T) {
+	now := time.Now()
+	hs256 := func(claims jwt.MapClaims) string {
+		return sign(t, jwt.SigningMethodHS256, []byte("secret"), claims)
+	}
+	mw, err := TokenValidateWithOptions("secret", WithValidation(ValidationOptions{
+		Issuers:        []string{"auth"},
+		Audiences:      []string{"api"},
+		MaxAge:         time.Hour,
+		RequiredClaims: []string{"sub"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{"sub": "user", "iss": "auth", "aud": "api", "iat": now.Unix(), "exp": now.Add(time.Minute).Unix()}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	for _, tc := range []struct {
+		name  string
+		token string
+		code  string
+	}{
+		{"valid", hs256(claims(nil)), ""},
+		{"missing", "", "UNAUTHORIZED"},
+		{"malformed", "not.a.token", "TOKEN_MALFORMED"},
+		{"signature", sign(t, jwt.SigningMethodHS256, []byte("other"), claims(nil)), "TOKEN_INVALID_SIGNATURE"},
+		{"expired", hs256(claims(jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()})), "TOKEN_EXPIRED"},
+		{"not before", hs256(claims(jwt.MapClaims{"nbf": now.Add(time.Minute).Unix()})), "TOKEN_NOT_VALID_YET"},
+		{"too old", hs256(claims(jwt.MapClaims{"iat": now.Add(-2 * time.Hour).Unix(), "exp": now.Add(time.Hour).Unix()})), "TOKEN_TOO_OLD"},
+		{"no iat", hs256(claims(jwt.MapClaims{"iat": nil})), "TOKEN_MISSING_CLAIM"},
+		{"issuer", hs256(claims(jwt.MapClaims{"iss": "other"})), "TOKEN_INVALID_ISSUER"},
+		{"audience", hs256(claims(jwt.MapClaims{"aud": []string{"billing"}})), "TOKEN_INVALID_AUDIENCE"},
+		{"required claim", hs256(claims(jwt.MapClaims{"sub": nil})), "TOKEN_MISSING_CLAIM"},
+		{"refresh token", hs256(claims(jwt.MapClaims{"typ": TokenTypeRefresh})), "TOKEN_INVALID_TYPE"},
+		{"bad exp", hs256(claims(jwt.MapClaims{"exp": "tomorrow"})), "TOKEN_MALFORMED"},
+	} {
+		status, code := serve(t, mw, tc.token)
+		if code != tc.code || code != "" && status != http.StatusUnauthorized {
+			t.Errorf("%s: status %d, code %q, want %q", tc.name, status, code, tc.code)
+		}
+	}
+
+	// a key missing from the key set is reported apart from a bad signature
+	v, err := NewKeySetValidator(errKeySet{err: ErrUnknownKeyID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, code := serve(t, v.Middleware, signRS256(t, generateRSA(t), "k1")); code != "TOKEN_UNKNOWN_KEY" {
+		t.Fatalf("unknown key: code %q", code)
+	}
+}
+
+func TestValidationLeeway(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	leeway := 10 * time.Second
+	opts := ValidationOptions{Leeway: leeway, MaxAge: time.Minute}
+	at := func(d time.Duration) int64 { return now.Add(d).Unix() }
+
+	for _, tc := range []struct {
+		name   string
+		claims jwt.MapClaims
+		want   error
+	}{
+		{"exp within leeway", jwt.MapClaims{"iat": at(0), "exp": at(-leeway + time.Second)}, nil},
+		{"exp at leeway", jwt.MapClaims{"iat": at(0), "exp": at(-leeway)}, ErrTokenExpired},
+		{"nbf at leeway", jwt.MapClaims{"iat": at(0), "nbf": at(leeway)}, nil},
+		{"nbf past leeway", jwt.MapClaims{"iat": at(0), "nbf": at(leeway + time.Second)}, ErrTokenNotValidYet},
+		{"iat at leeway", jwt.MapClaims{"iat": at(leeway)}, nil},
+		{"iat past leeway", jwt.MapClaims{"iat": at(leeway + time.Second)}, ErrTokenNotValidYet},
+		{"age at max", jwt.MapClaims{"iat": at(-time.Minute - leeway)}, nil},
+		{"age past max", jwt.MapClaims{"iat": at(-time.Minute - leeway - time.Second)}, ErrTokenTooOld},
+		{"no iat with max age", jwt.MapClaims{}, ErrTokenMissingClaim},
+	} {
+		token := &jwt.Token{Header: map[string]interface{}{}, Claims: tc.claims}
+		err := opts.validate(token, tc.claims, now)
+		if tc.want == nil && err != nil || tc.want != nil && !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	// without leeway a token expires exactly at exp
+	claims := jwt.MapClaims{"exp": at(0)}
+	if err := (ValidationOptions{}).validate(&jwt.Token{Header: map[string]interface{}{}}, claims, now); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("token at exp: %v", err)
+	}
+}
+
+func TestValidationTokenType(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		expected string
+		claim    string
+		header   string
+		want     error
+	}{
+		{"untyped", "", "", "", nil},
+		{"access", "", TokenTypeAccess, "", nil},
+		{"refresh by default", "", TokenTypeRefresh, "", ErrTokenType},
+		{"refresh in header", "", "", TokenTypeRefresh, ErrTokenType},
+		{"refresh expected", TokenTypeRefresh, TokenTypeRefresh, "", nil},
+		{"access expected", TokenTypeAccess, TokenTypeRefresh, "", ErrTokenType},
+		{"access expected untyped", TokenTypeAccess, "", "", ErrTokenType},
+	} {
+		claims := jwt.MapClaims{}
+		if tc.claim != "" {
+			claims["typ"] = tc.claim
+		}
+		token := &jwt.Token{Header: map[string]interface{}{}}
+		if tc.header != "" {
+			token.Header["typ"] = tc.header
+		}
+		err := ValidationOptions{TokenType: tc.expected}.validate(token, claims, time.Now())
+		if tc.want == nil && err != nil || tc.want != nil && !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package jwtutils
+
+import (
+	"fmt"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Signer issues tokens signed with a private key or an HMAC secret.
+type Signer struct {
+	method jwt.SigningMethod
+	key    interface{}
+	keyID  string
+}
+
+// NewSigner creates a Signer for key and algorithm alg: []byte with HS*, *rsa.PrivateKey with RS*/PS*,
+// *ecdsa.PrivateKey with ES*, ed25519.PrivateKey with EdDSA. keyID is put into the kid header if not empty.
+func NewSigner(key interface{}, alg string, keyID string) (*Signer, error) {
+	if err := checkAlgorithms(key, []string{alg}); err != nil {
+		return nil, fmt.Errorf("token signer: %w", err)
+	}
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return nil, fmt.Errorf("token signer: %w, alg: %v", ErrUnexpectedSigningMethod, alg)
+	}
+	return &Signer{method: method, key: key, keyID: keyID}, nil
+}
+
+// NewSignerFromPEM creates a Signer for a PEM encoded private key.
+func NewSignerFromPEM(data []byte, alg string, keyID string) (*Signer, error) {
+	key, err := PrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("token signer: %w", err)
+	}
+	return NewSigner(key, alg, keyID)
+}
+
+// Sign returns the signed token for claims.
+func (s *Signer) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.method, claims)
+	if s.keyID != "" {
+		token.Header["kid"] = s.keyID
+	}
+	return token.SignedString(s.key)
+}
+
+// CreateTokenPair is CreateTokenPair signed by s.
+func (s *Signer) CreateTokenPair(payload map[string]any, accExpSec int64, refExpSec int64) (*TokenPair, error) {
+	return createTokenPair(s, payload, accExpSec, refExpSec)
+}
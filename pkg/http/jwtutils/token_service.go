@@ -0,0 +1,183 @@
+package jwtutils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+)
+
+// Token types put into the typ claim.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// claims set by the token service itself, not copied from a refresh token into the next pair
+var reservedClaims = []string{"exp", "iat", "nbf", "jti", "typ", "fam", "iss", "aud", "authorized"}
+
+// TokenServiceConfig configures a TokenService.
+type TokenServiceConfig struct {
+	Signer     *Signer
+	Store      FamilyStore
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	Issuer     string
+	Audience   string
+	Leeway     time.Duration // clock skew when validating tokens
+}
+
+// TokenService issues access and refresh tokens with rotation of refresh tokens.
+// Every token has a typ claim and a unique jti; refresh tokens also carry the fam claim
+// identifying their family. Each refresh token can be used once: using it again revokes
+// the whole family, so a stolen token stops working for both the thief and the user.
+//
+// Access tokens are checked by Middleware or ParseAccess: typ must be access, iss and aud
+// must match the config. Other validators reject refresh tokens by default.
+type TokenService struct {
+	cfg       TokenServiceConfig
+	accesses  *Validator
+	refreshes *Validator
+}
+
+// NewTokenService creates a TokenService, e.g. with a store from NewRedisFamilyStore.
+func NewTokenService(cfg TokenServiceConfig) (*TokenService, error) {
+	if cfg.Signer == nil || cfg.Store == nil {
+		return nil, fmt.Errorf("token service: signer and store are required")
+	}
+	if cfg.AccessTTL <= 0 || cfg.RefreshTTL <= 0 {
+		return nil, fmt.Errorf("token service: access and refresh ttl are required")
+	}
+	s := &TokenService{cfg: cfg}
+	var err error
+	s.accesses, err = s.validator(TokenTypeAccess, "exp", "jti")
+	if err != nil {
+		return nil, err
+	}
+	s.refreshes, err = s.validator(TokenTypeRefresh, "exp", "jti", "fam")
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// validator checks tokens of type typ issued by this service.
+func (s *TokenService) validator(typ string, required ...string) (*Validator, error) {
+	opts := ValidationOptions{
+		Leeway:         s.cfg.Leeway,
+		RequiredClaims: required,
+		TokenType:      typ,
+	}
+	if s.cfg.Issuer != "" {
+		opts.Issuers = []string{s.cfg.Issuer}
+	}
+	if s.cfg.Audience != "" {
+		opts.Audiences = []string{s.cfg.Audience}
+	}
+	v, err := NewValidator(s.cfg.Signer.key, WithAlgorithms(s.cfg.Signer.method.Alg()), WithValidation(opts))
+	if err != nil {
+		return nil, fmt.Errorf("token service: %w", err)
+	}
+	return v, nil
+}
+
+// Middleware validates the bearer access token of each request, see TokenValidate.
+func (s *TokenService) Middleware(next http.Handler) http.Handler {
+	return s.accesses.Middleware(next)
+}
+
+// ParseAccess validates an access token issued by the service.
+func (s *TokenService) ParseAccess(accessToken string) (*jwt.Token, error) {
+	return s.accesses.Parse(accessToken)
+}
+
+// Issue starts a new token family, e.g. on login. payload is copied into both tokens.
+func (s *TokenService) Issue(ctx context.Context, payload map[string]any) (*TokenPair, error) {
+	family := uuid.NewString()
+	refreshID := uuid.NewString()
+	if err := s.cfg.Store.Create(ctx, family, refreshID, s.cfg.RefreshTTL); err != nil {
+		return nil, fmt.Errorf("token service: %w", err)
+	}
+	return s.pair(payload, family, refreshID)
+}
+
+// Refresh exchanges a refresh token for a new pair. The used refresh token is invalidated;
+// presenting it again fails with ErrTokenReused and revokes the family.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	claims, err := s.parseRefresh(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	family, _ := claims["fam"].(string)
+	jti, _ := claims["jti"].(string)
+
+	refreshID := uuid.NewString()
+	if err := s.cfg.Store.Rotate(ctx, family, jti, refreshID, s.cfg.RefreshTTL); err != nil {
+		return nil, err
+	}
+
+	payload := make(map[string]any, len(claims))
+	for k, v := range claims {
+		payload[k] = v
+	}
+	for _, k := range reservedClaims {
+		delete(payload, k)
+	}
+	return s.pair(payload, family, refreshID)
+}
+
+// Revoke invalidates the family of refreshToken, e.g. on logout.
+func (s *TokenService) Revoke(ctx context.Context, refreshToken string) error {
+	claims, err := s.parseRefresh(refreshToken)
+	if err != nil {
+		return err
+	}
+	family, _ := claims["fam"].(string)
+	return s.cfg.Store.Revoke(ctx, family)
+}
+
+func (s *TokenService) parseRefresh(refreshToken string) (jwt.MapClaims, error) {
+	token, err := s.refreshes.Parse(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return token.Claims.(jwt.MapClaims), nil
+}
+
+func (s *TokenService) pair(payload map[string]any, family string, refreshID string) (*TokenPair, error) {
+	now := time.Now()
+	access := s.claims(payload, TokenTypeAccess, uuid.NewString(), now, s.cfg.AccessTTL)
+	refresh := s.claims(payload, TokenTypeRefresh, refreshID, now, s.cfg.RefreshTTL)
+	refresh["fam"] = family
+
+	accessToken, err := s.cfg.Signer.Sign(access)
+	if err != nil {
+		return nil, fmt.Errorf("create signed access token string %v", err)
+	}
+	refreshToken, err := s.cfg.Signer.Sign(refresh)
+	if err != nil {
+		return nil, fmt.Errorf("create signed refresh token string %v", err)
+	}
+	return &TokenPair{accessToken, "bearer", int64(s.cfg.AccessTTL.Seconds()), refreshToken}, nil
+}
+
+func (s *TokenService) claims(payload map[string]any, typ string, jti string, now time.Time, ttl time.Duration) jwt.MapClaims {
+	claims := jwt.MapClaims{}
+	for k, v := range payload {
+		claims[k] = v
+	}
+	claims["typ"] = typ
+	claims["jti"] = jti
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+	if s.cfg.Issuer != "" {
+		claims["iss"] = s.cfg.Issuer
+	}
+	if s.cfg.Audience != "" {
+		claims["aud"] = s.cfg.Audience
+	}
+	return claims
+}
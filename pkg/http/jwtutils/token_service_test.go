@@ -0,0 +1,139 @@
+package jwtutils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func newTestTokenService(t *testing.T, secret string, audience string) *TokenService {
+	signer, err := NewSigner([]byte(secret), "HS256", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewTokenService(TokenServiceConfig{
+		Signer:     signer,
+		Store:      NewMemoryFamilyStore(),
+		AccessTTL:  time.Minute,
+		RefreshTTL: time.Hour,
+		Issuer:     "auth",
+		Audience:   audience,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestTokenServiceRotation(t *testing.T) {
+	ctx := context.Background()
+	s := newTestTokenService(t, "secret", "api")
+
+	first, err := s.Issue(ctx, map[string]any{"sub": "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := s.ParseAccess(second.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := token.Claims.(jwt.MapClaims)
+	if sub, _ := claims["sub"].(string); sub != "user" {
+		t.Fatalf("payload is not carried over: %v", claims)
+	}
+
+	// the used refresh token revokes the family
+	if _, err := s.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("want ErrTokenReused, got %v", err)
+	}
+	if _, err := s.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("want ErrTokenRevoked, got %v", err)
+	}
+}
+
+func TestTokenServiceTokenTypes(t *testing.T) {
+	ctx := context.Background()
+	s := newTestTokenService(t, "secret", "api")
+	pair, err := s.Issue(ctx, map[string]any{"sub": "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Refresh(ctx, pair.AccessToken); !errors.Is(err, ErrTokenType) {
+		t.Fatalf("access token used as refresh token: %v", err)
+	}
+	if _, err := s.ParseAccess(pair.RefreshToken); !errors.Is(err, ErrTokenType) {
+		t.Fatalf("refresh token used as access token: %v", err)
+	}
+
+	// validators without a token type reject refresh tokens too
+	for name, token := range map[string]string{"service": pair.RefreshToken, "legacy": legacyRefreshToken(t, "secret")} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		TokenValidate("secret")(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			t.Errorf("%s refresh token passed TokenValidate", name)
+		})).ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("%s refresh token: status %d", name, rec.Code)
+		}
+	}
+}
+
+func TestTokenServiceMiddleware(t *testing.T) {
+	s := newTestTokenService(t, "secret", "api")
+	other := newTestTokenService(t, "secret", "billing")
+	pair, err := s.Issue(context.Background(), map[string]any{"sub": "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, err := other.Issue(context.Background(), map[string]any{"sub": "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := ClaimsFromContext(r.Context()); !ok {
+			t.Error("claims are not in the context")
+		}
+	}))
+	for _, tc := range []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"access", pair.AccessToken, http.StatusOK},
+		{"refresh", pair.RefreshToken, http.StatusUnauthorized},
+		{"other audience", foreign.AccessToken, http.StatusUnauthorized},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.status)
+		}
+	}
+
+	// refresh tokens of another audience are not accepted either
+	if _, err := s.Refresh(context.Background(), foreign.RefreshToken); !errors.Is(err, ErrTokenAudience) {
+		t.Fatalf("want ErrTokenAudience, got %v", err)
+	}
+}
+
+func legacyRefreshToken(t *testing.T, secret string) string {
+	pair, err := CreateTokenPair(map[string]any{"sub": "user"}, secret, 60, 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair.RefreshToken
+}
@@ -0,0 +1,118 @@
+package jwtutils
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// FamilyStore keeps the current refresh token of each token family.
+// A family is the chain of refresh tokens issued from one login.
+type FamilyStore interface {
+	// Create starts a family with refresh token jti.
+	Create(ctx context.Context, family string, jti string, ttl time.Duration) error
+	// Rotate replaces the current refresh token oldJTI with newJTI. If oldJTI is not current,
+	// the token is reused: the family is revoked and ErrTokenReused returned.
+	// ErrTokenRevoked is returned for unknown, expired or revoked families.
+	Rotate(ctx context.Context, family string, oldJTI string, newJTI string, ttl time.Duration) error
+	// Revoke invalidates every refresh token of the family.
+	Revoke(ctx context.Context, family string) error
+}
+
+// rotateScript: 1 - rotated, 0 - no family, -1 - reuse detected and family revoked.
+var rotateScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return 0
+end
+if current ~= ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return -1
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// RedisFamilyStore is a FamilyStore in Redis, the client is created by redis.NewRedisClient.
+type RedisFamilyStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisFamilyStore creates a FamilyStore with keys starting with prefix.
+func NewRedisFamilyStore(client *redis.Client, prefix string) *RedisFamilyStore {
+	return &RedisFamilyStore{client: client, prefix: prefix}
+}
+
+func (s *RedisFamilyStore) Create(ctx context.Context, family string, jti string, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+family, jti, ttl).Err()
+}
+
+func (s *RedisFamilyStore) Rotate(ctx context.Context, family string, oldJTI string, newJTI string, ttl time.Duration) error {
+	res, err := rotateScript.Run(ctx, s.client, []string{s.prefix + family}, oldJTI, newJTI, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	switch res {
+	case 0:
+		return newTokenError(ErrTokenRevoked, nil)
+	case -1:
+		return newTokenError(ErrTokenReused, nil)
+	}
+	return nil
+}
+
+func (s *RedisFamilyStore) Revoke(ctx context.Context, family string) error {
+	return s.client.Del(ctx, s.prefix+family).Err()
+}
+
+// MemoryFamilyStore is a FamilyStore in memory for a single instance and tests.
+type MemoryFamilyStore struct {
+	mu       sync.Mutex
+	families map[string]memoryFamily
+}
+
+type memoryFamily struct {
+	jti     string
+	expires time.Time
+}
+
+// NewMemoryFamilyStore creates an empty MemoryFamilyStore.
+func NewMemoryFamilyStore() *MemoryFamilyStore {
+	return &MemoryFamilyStore{families: make(map[string]memoryFamily)}
+}
+
+func (s *MemoryFamilyStore) Create(_ context.Context, family string, jti string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.families[family] = memoryFamily{jti: jti, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryFamilyStore) Rotate(_ context.Context, family string, oldJTI string, newJTI string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.families[family]
+	if !ok || time.Now().After(f.expires) {
+		delete(s.families, family)
+		return newTokenError(ErrTokenRevoked, nil)
+	}
+	if f.jti != oldJTI {
+		delete(s.families, family)
+		return newTokenError(ErrTokenReused, nil)
+	}
+	s.families[family] = memoryFamily{jti: newJTI, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryFamilyStore) Revoke(_ context.Context, family string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.families, family)
+	return nil
+}
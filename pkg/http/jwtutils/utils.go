@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	httpErrors "github.com/mlplabs/common-go-pkg/pkg/http/errors"
 	"net/http"
 	"strings"
 	"time"
@@ -44,17 +46,27 @@ var (
 )
 
 type handler struct {
-	handler http.Handler
-	key     string
+	handler   http.Handler
+	validator *Validator
 }
 
-// TokenValidate is a middleware for jwt token validation.
+// TokenValidate is a middleware for jwt token validation with an HMAC secret.
+// For claim checks use TokenValidateWithOptions, for RSA, ECDSA and Ed25519 signed
+// tokens NewValidator(...).Middleware. Failures are rendered by errors.SetError, see TokenError.
 func TokenValidate(key string) func(http.Handler) http.Handler {
-	f := func(h http.Handler) http.Handler {
-		handler := handler{key: key, handler: h}
-		return validate(handler)
+	// cannot fail: the default algorithms always match an HMAC secret
+	v, _ := NewValidator([]byte(key))
+	return v.Middleware
+}
+
+// TokenValidateWithOptions is TokenValidate with validation options, e.g. WithValidation.
+// It fails when the algorithms set by WithAlgorithms do not match an HMAC secret.
+func TokenValidateWithOptions(key string, opts ...Option) (func(http.Handler) http.Handler, error) {
+	v, err := NewValidator([]byte(key), opts...)
+	if err != nil {
+		return nil, err
 	}
-	return f
+	return v.Middleware, nil
 }
 
 func validate(h handler) http.Handler {
@@ -64,25 +76,19 @@ func validate(h handler) http.Handler {
 func (h *handler) middlewareFuncJWT(w http.ResponseWriter, r *http.Request) {
 	tokenString := ExtractToken(r)
 	if tokenString == "" {
-		RenderJSONWithStatus(w, JSON{"error": "требуется авторизация"}, http.StatusUnauthorized)
+		httpErrors.SetError(w, r, newTokenError(ErrTokenMissing, nil))
 		return
 	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("token validator: %w, alg: %v", ErrUnexpectedSigningMethod, token.Header["alg"])
-		}
-		return []byte(h.key), nil
-	})
+	token, err := h.validator.Parse(tokenString)
 	if err != nil {
-		RenderJSONWithStatus(w, JSON{"error": err.Error()}, http.StatusUnauthorized)
-		return
-	}
-	if !token.Valid {
-		RenderJSONWithStatus(w, JSON{"error": "невалидный токен"}, http.StatusUnauthorized)
+		httpErrors.SetError(w, r, err)
 		return
 	}
 	ctx := r.Context()
+	if claims, ok := token.Claims.(jwt.MapClaims); ok {
+		ctx = ContextWithClaims(ctx, claims)
+	}
 	h.handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
@@ -95,6 +101,10 @@ func ExtractToken(r *http.Request) string {
 }
 
 func CreateTokenPair(payload map[string]any, secretKey string, accExpSec int64, refExpSec int64) (*TokenPair, error) {
+	return createTokenPair(&Signer{method: jwt.SigningMethodHS256, key: []byte(secretKey)}, payload, accExpSec, refExpSec)
+}
+
+func createTokenPair(s *Signer, payload map[string]any, accExpSec int64, refExpSec int64) (*TokenPair, error) {
 	accessClaims := jwt.MapClaims{}
 	accessClaims["authorized"] = true
 	accessClaims["exp"] = time.Now().Add(time.Duration(accExpSec) * time.Second).Unix() //Token expires after 15 minutes
@@ -108,15 +118,17 @@ func CreateTokenPair(payload map[string]any, secretKey string, accExpSec int64,
 			refreshClaims[k] = v
 		}
 	}
+	// types and ids are set after the payload so that a refresh token can't pass as an access token
+	accessClaims["typ"] = TokenTypeAccess
+	accessClaims["jti"] = uuid.NewString()
+	refreshClaims["typ"] = TokenTypeRefresh
+	refreshClaims["jti"] = uuid.NewString()
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-
-	accessTokenString, err := accessToken.SignedString([]byte(secretKey))
+	accessTokenString, err := s.Sign(accessClaims)
 	if err != nil {
 		return nil, fmt.Errorf("create signed access token string %v", err)
 	}
-	refreshTokenSting, err := refreshToken.SignedString([]byte(secretKey))
+	refreshTokenSting, err := s.Sign(refreshClaims)
 	if err != nil {
 		return nil, fmt.Errorf("create signed refresh token string %v", err)
 	}
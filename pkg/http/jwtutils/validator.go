@@ -0,0 +1,135 @@
+package jwtutils
+
+import (
+	"crypto"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+
+	"github.com/mlplabs/common-go-pkg/pkg/config"
+)
+
+// KeySet provides keys for signature verification.
+type KeySet interface {
+	// Key returns the key for the token header values kid and alg.
+	Key(kid string, alg string) (interface{}, error)
+}
+
+type staticKey struct {
+	key interface{}
+}
+
+func (k staticKey) Key(string, string) (interface{}, error) {
+	return k.key, nil
+}
+
+// Validator checks token signatures with a key or a KeySet and an explicit allowlist of algorithms.
+// It is safe for concurrent use.
+type Validator struct {
+	keys       KeySet
+	algorithms []string
+	options    ValidationOptions
+}
+
+// Option configures a Validator.
+type Option func(*Validator)
+
+// WithAlgorithms restricts accepted signing algorithms, e.g. WithAlgorithms("RS256").
+// By default every algorithm matching the key type is accepted, see AlgorithmsForKey.
+func WithAlgorithms(algorithms ...string) Option {
+	return func(v *Validator) {
+		v.algorithms = algorithms
+	}
+}
+
+// NewValidator creates a Validator for key: []byte for HMAC secrets,
+// *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey. Private keys are reduced to their public part.
+func NewValidator(key interface{}, opts ...Option) (*Validator, error) {
+	if signer, ok := key.(crypto.Signer); ok {
+		key = signer.Public()
+	}
+	v := &Validator{keys: staticKey{key: key}, algorithms: AlgorithmsForKey(key)}
+	for _, opt := range opts {
+		opt(v)
+	}
+	if err := checkAlgorithms(key, v.algorithms); err != nil {
+		return nil, fmt.Errorf("token validator: %w", err)
+	}
+	return v, nil
+}
+
+// NewKeySetValidator creates a Validator selecting keys from keys by the kid header, e.g. a JWKS.
+// By default RSA, ECDSA and Ed25519 algorithms are accepted; HMAC has to be allowed explicitly.
+func NewKeySetValidator(keys KeySet, opts ...Option) (*Validator, error) {
+	v := &Validator{keys: keys, algorithms: asymmetricAlgorithms()}
+	for _, opt := range opts {
+		opt(v)
+	}
+	if len(v.algorithms) == 0 {
+		return nil, fmt.Errorf("token validator: %w", ErrNoAlgorithmsAllowed)
+	}
+	return v, nil
+}
+
+// NewValidatorFromConfig creates a Validator for the public key in config.Auth.PublicKeyBase64.
+func NewValidatorFromConfig(cfg *config.Auth, opts ...Option) (*Validator, error) {
+	key, err := PublicKeyFromBase64(cfg.PublicKeyBase64)
+	if err != nil {
+		return nil, fmt.Errorf("token validator: %w", err)
+	}
+	return NewValidator(key, opts...)
+}
+
+// Algorithms returns the accepted signing algorithms.
+func (v *Validator) Algorithms() []string {
+	return slices.Clone(v.algorithms)
+}
+
+// Parse parses tokenString, verifies its signature and checks the claims by ValidationOptions.
+// Errors are *TokenError.
+func (v *Validator) Parse(tokenString string) (*jwt.Token, error) {
+	parser := &jwt.Parser{ValidMethods: v.algorithms, SkipClaimsValidation: true}
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if !slices.Contains(v.algorithms, token.Method.Alg()) {
+			return nil, fmt.Errorf("token validator: %w, alg: %v", ErrUnexpectedSigningMethod, token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, err := v.keys.Key(kid, token.Method.Alg())
+		if err != nil {
+			return nil, fmt.Errorf("token validator: %w", err)
+		}
+		// the key type must match the algorithm, otherwise e.g. an RSA public key could serve as an HMAC secret
+		if err := checkAlgorithms(key, []string{token.Method.Alg()}); err != nil {
+			return nil, fmt.Errorf("token validator: %w", err)
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, parseError(err)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, newTokenError(ErrTokenMalformed, nil)
+	}
+	if err := v.options.validate(token, claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// TokenValidateWithKeySet is TokenValidate for tokens signed by one of keys, e.g. a JWKS.
+func TokenValidateWithKeySet(keys KeySet, opts ...Option) (func(http.Handler) http.Handler, error) {
+	v, err := NewKeySetValidator(keys, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return v.Middleware, nil
+}
+
+// Middleware validates the bearer token of each request, see TokenValidate.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return validate(handler{handler: next, validator: v})
+}
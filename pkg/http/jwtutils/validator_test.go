@@ -0,0 +1,159 @@
+package jwtutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// serve passes token through the validator middleware and returns the status and error code.
+func serve(t *testing.T, h func(http.Handler) http.Handler, token string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	h(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(rec, req)
+	if rec.Code == http.StatusOK {
+		return rec.Code, ""
+	}
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("status %d, body %q: %v", rec.Code, rec.Body, err)
+	}
+	return rec.Code, body.Error.Code
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "user", "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+func TestValidatorAsymmetric(t *testing.T) {
+	rsaKey := generateRSA(t)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		alg   string
+		key   interface{}
+		other interface{}
+	}{
+		{"RS256", rsaKey, generateRSA(t)},
+		{"ES256", ecKey, rsaKey},
+		{"EdDSA", edKey, ecKey},
+	} {
+		signer, err := NewSigner(tc.key, tc.alg, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := signer.Sign(validClaims())
+		if err != nil {
+			t.Fatal(err)
+		}
+		// private keys are reduced to their public part
+		v, err := NewValidator(tc.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := v.Parse(token); err != nil {
+			t.Fatalf("%s: %v", tc.alg, err)
+		}
+
+		other, err := NewValidator(tc.other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := other.Parse(token); !errors.Is(err, ErrTokenSignature) {
+			t.Fatalf("%s verified by another key: %v", tc.alg, err)
+		}
+	}
+}
+
+func TestValidatorRejectsAlgorithmConfusion(t *testing.T) {
+	key := generateRSA(t)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	// the public key is known to everyone, so an HS256 token "signed" with it must not pass
+	forged := sign(t, jwt.SigningMethodHS256, publicPEM, validClaims())
+
+	v, err := NewValidator(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Parse(forged); !errors.Is(err, ErrTokenSignature) {
+		t.Fatalf("HS256 token signed with the RSA public key: %v", err)
+	}
+	if _, err := NewValidator(&key.PublicKey, WithAlgorithms("RS256", "HS256")); !errors.Is(err, ErrAlgorithmNotAllowed) {
+		t.Fatalf("HS256 allowed for an RSA key: %v", err)
+	}
+
+	// a key set may allow HMAC, the key type is still checked against the algorithm
+	ks, err := NewKeySetValidator(staticKey{key: &key.PublicKey}, WithAlgorithms("RS256", "HS256"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Parse(forged); err == nil {
+		t.Fatal("key set validator accepted an HS256 token for an RSA key")
+	}
+	if _, err := ks.Parse(signRS256(t, key, "")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidatorRejectsNone(t *testing.T) {
+	token := sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims())
+	v, err := NewValidator([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Parse(token); !errors.Is(err, ErrTokenSignature) {
+		t.Fatalf("alg none: %v", err)
+	}
+}
+
+func TestTokenValidateMiddleware(t *testing.T) {
+	mw := TokenValidate("secret")
+	if status, code := serve(t, mw, sign(t, jwt.SigningMethodHS256, []byte("secret"), validClaims())); status != http.StatusOK {
+		t.Fatalf("valid token: status %d, code %s", status, code)
+	}
+	if status, code := serve(t, mw, sign(t, jwt.SigningMethodHS512, []byte("secret"), validClaims())); status != http.StatusOK {
+		t.Fatalf("HS512 token: status %d, code %s", status, code)
+	}
+	if _, err := TokenValidateWithOptions("secret", WithAlgorithms("RS256")); !errors.Is(err, ErrAlgorithmNotAllowed) {
+		t.Fatalf("RS256 allowed for an HMAC secret: %v", err)
+	}
+}
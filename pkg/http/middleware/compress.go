@@ -0,0 +1,291 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/mlplabs/common-go-pkg/pkg/http/errors"
+	"github.com/mlplabs/common-go-pkg/pkg/http/errors/custom"
+)
+
+const (
+	EncodingZstd    = "zstd"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+
+	defaultMinSize             = 1024
+	defaultMaxDecompressedSize = 10 << 20
+)
+
+var defaultExcludedContentTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zstd",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"text/event-stream",
+}
+
+// CompressOptions - настройки сжатия ответов.
+type CompressOptions struct {
+	// MinSize - ответы меньше этого размера отправляются без сжатия.
+	// Если обработчик вызывает Flush раньше, чем накопится MinSize, решение принимается
+	// по уже записанному и ответ сжимается: потоковый ответ не может ждать MinSize.
+	MinSize int
+	// MaxDecompressedSize - предельный размер распакованного тела запроса, по умолчанию 10 МБ.
+	// Защищает от gzip-бомб; при превышении чтение тела возвращает *http.MaxBytesError.
+	MaxDecompressedSize int64
+	// Encodings - поддерживаемые кодировки в порядке предпочтения.
+	Encodings []string
+	// ExcludedContentTypes - префиксы Content-Type, которые не сжимаются (уже сжатые форматы).
+	ExcludedContentTypes []string
+}
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Compress - middleware сжатия ответов по Accept-Encoding (zstd, gzip, deflate)
+// и распаковки запросов с Content-Encoding: gzip.
+//
+//	mux.Use(middleware.Compress(middleware.CompressOptions{}))
+func Compress(opts CompressOptions) func(http.Handler) http.Handler {
+	if opts.MinSize <= 0 {
+		opts.MinSize = defaultMinSize
+	}
+	if len(opts.Encodings) == 0 {
+		opts.Encodings = []string{EncodingZstd, EncodingGzip, EncodingDeflate}
+	}
+	if opts.MaxDecompressedSize <= 0 {
+		opts.MaxDecompressedSize = defaultMaxDecompressedSize
+	}
+	if opts.ExcludedContentTypes == nil {
+		opts.ExcludedContentTypes = defaultExcludedContentTypes
+	}
+
+	pools := map[string]*sync.Pool{
+		EncodingGzip: {New: func() any {
+			w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+			return w
+		}},
+		EncodingDeflate: {New: func() any {
+			w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+			return w
+		}},
+		EncodingZstd: {New: func() any {
+			w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+			return w
+		}},
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := decompressRequest(w, r, opts.MaxDecompressedSize); err != nil {
+				errors.SetError(w, r, custom.NewBadRequest(err))
+				return
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiate(r.Header.Get("Accept-Encoding"), opts.Encodings)
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				opts:           &opts,
+				pool:           pools[encoding],
+				encoding:       encoding,
+			}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+func decompressRequest(w http.ResponseWriter, r *http.Request, limit int64) error {
+	if !strings.EqualFold(r.Header.Get("Content-Encoding"), EncodingGzip) || r.Body == nil {
+		return nil
+	}
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		return err
+	}
+	r.Body = http.MaxBytesReader(w, struct {
+		io.Reader
+		io.Closer
+	}{zr, r.Body}, limit)
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	return nil
+}
+
+// negotiate выбирает кодировку с наибольшим q из поддерживаемых.
+func negotiate(acceptEncoding string, supported []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		weights[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range supported {
+		q, ok := weights[enc]
+		if !ok {
+			q, ok = weights["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// compressWriter копит начало ответа до MinSize и только тогда решает, сжимать ли его.
+type compressWriter struct {
+	http.ResponseWriter
+	opts     *CompressOptions
+	pool     *sync.Pool
+	encoding string
+	enc      encoder
+	buf      []byte
+	status   int
+	decided  bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.status != 0 {
+		return
+	}
+	// информационные ответы (103 Early Hints и т.п.) не окончательные, за ними следует настоящий статус
+	if code >= 100 && code < http.StatusOK && code != http.StatusSwitchingProtocols {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.status = code
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.opts.MinSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush отправляет накопленные данные, не дожидаясь MinSize, нужен для потоковых ответов.
+// Сжатие при этом включается и для ответа меньше MinSize, см. CompressOptions.MinSize.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(true)
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	h := cw.Header()
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	if compress && h.Get("Content-Encoding") == "" && cw.compressible(h.Get("Content-Type")) {
+		cw.enc = cw.pool.Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		// сжатое представление побайтно отличается от исходного, строгий ETag для него неверен
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
+
+func (cw *compressWriter) compressible(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range cw.opts.ExcludedContentTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return strings.HasPrefix(contentType, "image/svg+xml")
+		}
+	}
+	return true
+}
+
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 {
+			// обработчик ничего не записал
+			return
+		}
+		cw.decide(false)
+	}
+	if cw.enc != nil {
+		cw.enc.Close()
+		cw.enc.Reset(nil)
+		cw.pool.Put(cw.enc)
+		cw.enc = nil
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DeadlineHeader - оставшееся время на обработку запроса в миллисекундах.
+// Клиент (pkg/http/client) выставляет его по дедлайну контекста.
+const DeadlineHeader = "X-Request-Timeout-Ms"
+
+// Deadline - middleware, ограничивающая контекст запроса временем из DeadlineHeader,
+// чтобы дедлайн вызывающего сервиса уменьшался на каждом переходе.
+// maxTimeout, если задан, ограничивает время обработки и для запросов без заголовка.
+func Deadline(maxTimeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout, limited := maxTimeout, maxTimeout > 0
+			if ms, err := strconv.ParseInt(r.Header.Get(DeadlineHeader), 10, 64); err == nil && ms >= 0 {
+				remaining := time.Duration(ms) * time.Millisecond
+				if !limited || remaining < timeout {
+					timeout, limited = remaining, true
+				}
+			}
+			if !limited {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
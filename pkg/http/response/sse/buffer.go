@@ -0,0 +1,61 @@
+package sse
+
+import (
+	"strconv"
+	"sync"
+)
+
+// ReplayBuffer stores sent events so a reconnecting client can resume from Last-Event-ID.
+type ReplayBuffer interface {
+	// Append stores the event and returns it with an ID assigned when the event had none.
+	Append(ev Event) Event
+	// Since returns events sent after lastEventID. ok is false when lastEventID is unknown
+	// (evicted or never sent), in that case the client can't be resumed consistently.
+	Since(lastEventID string) (events []Event, ok bool)
+}
+
+// MemoryBuffer is an in-memory ring ReplayBuffer holding the last size events.
+type MemoryBuffer struct {
+	mu     sync.Mutex
+	events []Event
+	size   int
+	seq    uint64
+}
+
+// NewMemoryBuffer creates MemoryBuffer for size events.
+func NewMemoryBuffer(size int) *MemoryBuffer {
+	if size <= 0 {
+		size = 1
+	}
+	return &MemoryBuffer{size: size, events: make([]Event, 0, size)}
+}
+
+func (b *MemoryBuffer) Append(ev Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	if ev.ID == "" {
+		ev.ID = strconv.FormatUint(b.seq, 10)
+	}
+	if len(b.events) == b.size {
+		copy(b.events, b.events[1:])
+		b.events = b.events[:len(b.events)-1]
+	}
+	b.events = append(b.events, ev)
+	return ev
+}
+
+func (b *MemoryBuffer) Since(lastEventID string) ([]Event, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := len(b.events) - 1; i >= 0; i-- {
+		if b.events[i].ID == lastEventID {
+			res := make([]Event, len(b.events)-i-1)
+			copy(res, b.events[i+1:])
+			return res, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,242 @@
+package sse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	httpErrors "github.com/mlplabs/common-go-pkg/pkg/http/errors"
+)
+
+const defaultHeartbeat = 15 * time.Second
+
+var (
+	// ErrClosed is returned by Send when the client has disconnected or the handler has returned.
+	ErrClosed = errors.New("sse: stream closed")
+	// ErrInvalidField is returned for an event ID or name containing a line break,
+	// which would let it inject extra fields into the stream.
+	ErrInvalidField = errors.New("sse: line break in event id or name")
+)
+
+// Event is a single Server-Sent Event.
+// Data of type string or []byte is sent as is, any other value is encoded as JSON.
+type Event struct {
+	ID    string
+	Event string
+	Data  any
+	Retry time.Duration
+}
+
+// Options - settings of an event stream.
+type Options struct {
+	// Heartbeat is the interval of comment lines keeping idle connections and proxies alive.
+	Heartbeat time.Duration
+	// Retry is the reconnection delay sent to the client when the stream opens.
+	Retry time.Duration
+	// Buffer, when set, records sent events and replays them after Last-Event-ID on reconnect.
+	Buffer ReplayBuffer
+}
+
+// Stream publishes events to one connected client. It's safe for concurrent use.
+type Stream struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	rc     *http.ResponseController
+	buffer ReplayBuffer
+	missed bool
+	mu     sync.Mutex
+	closed bool // set when the handler returns, the ResponseWriter must not be used after that
+}
+
+// Handler opens an event stream and passes it to ctrlFunc, which publishes events until
+// it returns or the client disconnects. An error returned by ctrlFunc is sent
+// as an "error" event carrying the standard error envelope.
+//
+// The server write timeout is lifted for the connection, so streams outlive
+// server.Server defaults.
+//
+// When the client reconnects with a Last-Event-ID the buffer no longer holds
+// (or with no Buffer configured), nothing is replayed and Stream.ReplayMissed reports true,
+// so ctrlFunc can send a full snapshot instead.
+func Handler(opts Options, ctrlFunc func(r *http.Request, s *Stream) error) http.HandlerFunc {
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = defaultHeartbeat
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		s := &Stream{
+			w:      w,
+			r:      r,
+			rc:     http.NewResponseController(w),
+			buffer: opts.Buffer,
+		}
+		if err := s.rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			httpErrors.SetError(w, r, err)
+			return
+		}
+
+		var replay []Event
+		if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+			ok := false
+			if opts.Buffer != nil {
+				replay, ok = opts.Buffer.Since(lastID)
+			}
+			s.missed = !ok
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("Connection", "keep-alive")
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		if opts.Retry > 0 {
+			s.write([]byte("retry: " + strconv.FormatInt(opts.Retry.Milliseconds(), 10) + "\n\n"))
+		}
+		for _, ev := range replay {
+			if err := s.send(ev); err != nil {
+				return
+			}
+		}
+
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.heartbeat(opts.Heartbeat, done)
+		}()
+		defer func() {
+			close(done)
+			wg.Wait()
+			s.mu.Lock()
+			s.closed = true
+			s.mu.Unlock()
+		}()
+
+		if err := ctrlFunc(r, s); err != nil && !errors.Is(err, ErrClosed) {
+			_, data := httpErrors.NewResponse(r, err)
+			_ = s.send(Event{Event: "error", Data: data})
+		}
+	}
+}
+
+// Send publishes the event, recording it in the replay buffer first.
+func (s *Stream) Send(ev Event) error {
+	if err := s.r.Context().Err(); err != nil {
+		return ErrClosed
+	}
+	if !validFields(ev) {
+		return ErrInvalidField
+	}
+	if s.buffer != nil {
+		ev = s.buffer.Append(ev)
+	}
+	return s.send(ev)
+}
+
+// ReplayMissed reports that the client asked to resume from a Last-Event-ID
+// that could not be replayed, so it may have missed events.
+func (s *Stream) ReplayMissed() bool {
+	return s.missed
+}
+
+// Done is closed when the client disconnects.
+func (s *Stream) Done() <-chan struct{} {
+	return s.r.Context().Done()
+}
+
+func (s *Stream) send(ev Event) error {
+	msg, err := encode(ev)
+	if err != nil {
+		return err
+	}
+	return s.write(msg)
+}
+
+func (s *Stream) write(msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if _, err := s.w.Write(msg); err != nil {
+		return ErrClosed
+	}
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("sse: flush: %w", err)
+	}
+	return nil
+}
+
+func (s *Stream) heartbeat(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-s.Done():
+			return
+		case <-ticker.C:
+			if err := s.write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func encode(ev Event) ([]byte, error) {
+	var data []byte
+	switch v := ev.Data.(type) {
+	case nil:
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		var err error
+		data, err = json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("sse: encode event data: %w", err)
+		}
+	}
+
+	if !validFields(ev) {
+		return nil, ErrInvalidField
+	}
+
+	buf := &bytes.Buffer{}
+	if ev.ID != "" {
+		buf.WriteString("id: " + ev.ID + "\n")
+	}
+	if ev.Event != "" {
+		buf.WriteString("event: " + ev.Event + "\n")
+	}
+	if ev.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+	// SSE treats \r\n, \r and \n alike as line ends
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	data = bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func validFields(ev Event) bool {
+	return !strings.ContainsAny(ev.ID, "\r\n") && !strings.ContainsAny(ev.Event, "\r\n")
+}
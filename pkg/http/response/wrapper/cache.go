@@ -0,0 +1,132 @@
+package wrapper
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mlplabs/common-go-pkg/pkg/http/errors/custom"
+)
+
+// CachePolicy - HTTP caching settings of a route.
+type CachePolicy struct {
+	// ETag computes a strong ETag from the encoded body unless the handler set one.
+	ETag bool
+	// CacheControl is sent as is, e.g. "private, max-age=60" or "no-cache".
+	CacheControl string
+}
+
+// WithCache returns a wrapper for the routes sharing the cache policy:
+//
+//	cached := rw.WithCache(wrapper.CachePolicy{ETag: true, CacheControl: "max-age=60"})
+//	r.Get("/catalog", cached.DataList(h.List))
+//
+// Successful GET and HEAD responses get ETag/Cache-Control and answer
+// If-None-Match and If-Modified-Since with 304 Not Modified.
+// ETag and Last-Modified set by the handler (see Result.WithETag) take precedence.
+func (rw *Wrapper) WithCache(policy CachePolicy) *Wrapper {
+	return &Wrapper{cache: &policy}
+}
+
+// WithETag sets ETag of the response. Use it when the version of a resource is known.
+func (res *Result) WithETag(etag string) *Result {
+	return res.WithHeader("ETag", quoteETag(etag))
+}
+
+// WithLastModified sets Last-Modified of the response.
+func (res *Result) WithLastModified(t time.Time) *Result {
+	return res.WithHeader("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// StrongETag returns a strong ETag of body.
+func StrongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// CheckPreconditions validates If-Match and If-Unmodified-Since of a modifying request
+// against the current state of the resource. The returned error is a 412 for SetError:
+//
+//	if err := wrapper.CheckPreconditions(r, item.Version, item.UpdatedAt); err != nil {
+//		return err
+//	}
+//
+// If-Match fails when etag is empty: the resource is unknown or missing, so no tag can match.
+// Zero lastModified skips the If-Unmodified-Since check.
+func CheckPreconditions(r *http.Request, etag string, lastModified time.Time) error {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if etag == "" || !matchETag(ifMatch, quoteETag(etag), false) {
+			return custom.NewPreconditionFailed(nil)
+		}
+		return nil
+	}
+	if since := r.Header.Get("If-Unmodified-Since"); since != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(since)
+		if err == nil && lastModified.Truncate(time.Second).After(t) {
+			return custom.NewPreconditionFailed(nil)
+		}
+	}
+	return nil
+}
+
+// apply sets caching headers and reports whether the client copy is fresh.
+func (p *CachePolicy) apply(w http.ResponseWriter, r *http.Request, body []byte) bool {
+	h := w.Header()
+	if p.CacheControl != "" {
+		h.Set("Cache-Control", p.CacheControl)
+	}
+	etag := h.Get("ETag")
+	if etag == "" && p.ETag {
+		etag = StrongETag(body)
+		h.Set("ETag", etag)
+	}
+
+	if r == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etag != "" && matchETag(inm, etag, true)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		lm, err := http.ParseTime(h.Get("Last-Modified"))
+		if err != nil {
+			return false
+		}
+		t, err := http.ParseTime(ims)
+		return err == nil && !lm.After(t)
+	}
+	return false
+}
+
+// matchETag checks etag against a header list. weak enables weak comparison (If-None-Match).
+func matchETag(header string, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	} else if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		} else if strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
@@ -0,0 +1,121 @@
+package wrapper
+
+import (
+	"net/http"
+
+	"github.com/mlplabs/common-go-pkg/pkg/http/errors"
+)
+
+// StatusCoder can be implemented by handler data to choose the success status code.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Result is a response envelope a handler may return instead of bare data
+// to set the status code, headers and cookies. Data is wrapped as usual.
+type Result struct {
+	Status  int
+	Header  http.Header
+	Cookies []*http.Cookie
+	Data    interface{}
+}
+
+// NewResult creates Result with status and data.
+func NewResult(status int, data interface{}) *Result {
+	return &Result{Status: status, Data: data, Header: http.Header{}}
+}
+
+// WithHeader adds a response header.
+func (res *Result) WithHeader(key, value string) *Result {
+	if res.Header == nil {
+		res.Header = http.Header{}
+	}
+	res.Header.Add(key, value)
+	return res
+}
+
+// WithCookie adds a cookie to the response.
+func (res *Result) WithCookie(cookie *http.Cookie) *Result {
+	res.Cookies = append(res.Cookies, cookie)
+	return res
+}
+
+// resolve applies headers and cookies of a Result and returns the payload with its status.
+func resolve(w http.ResponseWriter, data interface{}) (interface{}, int) {
+	var res *Result
+	switch v := data.(type) {
+	case *Result:
+		res = v
+	case Result:
+		res = &v
+	case StatusCoder:
+		return data, v.StatusCode()
+	default:
+		return data, http.StatusOK
+	}
+	if res == nil {
+		return nil, http.StatusOK
+	}
+
+	for k, values := range res.Header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	for _, c := range res.Cookies {
+		http.SetCookie(w, c)
+	}
+	if res.Status == 0 {
+		return res.Data, http.StatusOK
+	}
+	return res.Data, res.Status
+}
+
+// Created responds 201 with Location pointing to the new resource and data wrapped in a data section.
+func (rw *Wrapper) Created(ctrlFunc func(w http.ResponseWriter, r *http.Request) (interface{}, string, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, location, err := ctrlFunc(w, r)
+		if err != nil {
+			errors.SetError(w, r, err)
+			return
+		}
+		data, _ = resolve(w, data)
+		if location != "" {
+			w.Header().Set("Location", location)
+		}
+		rw.responseStatus(w, r, http.StatusCreated, Data{
+			Data: data,
+		})
+	}
+}
+
+// Accepted responds 202 for work that continues in background.
+// statusURL is where the client polls the operation state, it's sent in Location.
+func (rw *Wrapper) Accepted(ctrlFunc func(w http.ResponseWriter, r *http.Request) (interface{}, string, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, statusURL, err := ctrlFunc(w, r)
+		if err != nil {
+			errors.SetError(w, r, err)
+			return
+		}
+		data, _ = resolve(w, data)
+		if statusURL != "" {
+			w.Header().Set("Location", statusURL)
+		}
+		rw.responseStatus(w, r, http.StatusAccepted, Data{
+			Data: data,
+		})
+	}
+}
+
+// NoContent responds 204 without body.
+func (rw *Wrapper) NoContent(ctrlFunc func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := ctrlFunc(w, r)
+		if err != nil {
+			errors.SetError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,214 @@
+package wrapper
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mlplabs/common-go-pkg/pkg/http/errors"
+)
+
+// StreamFormat defines how the items of a stream are written to the client.
+type StreamFormat int
+
+const (
+	// StreamNDJSON writes one JSON document per line (application/x-ndjson).
+	// A mid-stream error is reported as a final {"error": {...}} line.
+	StreamNDJSON StreamFormat = iota
+	// StreamJSONArray writes {"data":[...],"count":N} incrementally.
+	// A mid-stream error closes the array and adds an "error" field instead of "count".
+	StreamJSONArray
+)
+
+const (
+	// StreamStatusTrailer is the HTTP trailer carrying the final stream state: "ok" or "error".
+	StreamStatusTrailer = "X-Stream-Status"
+
+	defaultFlushEvery    = 100
+	defaultFlushInterval = time.Second
+)
+
+// StreamOptions - stream output settings.
+type StreamOptions struct {
+	Format StreamFormat
+	// FlushEvery flushes the response after this many items.
+	FlushEvery int
+	// FlushInterval flushes the response when this much time passed since the last flush.
+	FlushInterval time.Duration
+}
+
+// StreamItem is an item of a channel based stream.
+type StreamItem[T any] struct {
+	Value T
+	Err   error
+}
+
+// FromChan adapts a channel to a stream sequence. The stream ends when the channel is closed
+// or ctx is done, e.g. when the client disconnects while the producer is idle.
+func FromChan[T any](ctx context.Context, ch <-chan T) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-ch:
+				if !ok || !yield(v, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// FromItemChan adapts a channel of stream items to a stream sequence.
+// The stream ends when the channel is closed, ctx is done or after the first error.
+func FromItemChan[T any](ctx context.Context, ch <-chan StreamItem[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case res, ok := <-ch:
+				if !ok || !yield(res.Value, res.Err) || res.Err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Stream writes items of the sequence returned by ctrlFunc without buffering the whole list.
+// An error returned by ctrlFunc itself is sent with SetError as usual; once streaming started
+// the status is already 200 and errors are reported in the body and the StreamStatusTrailer.
+// Iteration stops at the next item after the client disconnects; a sequence that may wait
+// for its producer should watch r.Context() itself, as FromChan and FromItemChan do.
+func Stream[T any](opts StreamOptions, ctrlFunc func(r *http.Request) (iter.Seq2[T, error], error)) http.HandlerFunc {
+	if opts.FlushEvery <= 0 {
+		opts.FlushEvery = defaultFlushEvery
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultFlushInterval
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		seq, err := ctrlFunc(r)
+		if err != nil {
+			errors.SetError(w, r, err)
+			return
+		}
+
+		sw := newStreamWriter(w, r, opts)
+		sw.begin()
+
+		ctx := r.Context()
+		for item, err := range seq {
+			if ctx.Err() != nil {
+				// client is gone, nobody to report to
+				return
+			}
+			if err != nil {
+				sw.fail(err)
+				return
+			}
+			if err := sw.item(item); err != nil {
+				sw.fail(err)
+				return
+			}
+		}
+		sw.end()
+	}
+}
+
+type streamWriter struct {
+	w         http.ResponseWriter
+	r         *http.Request
+	rc        *http.ResponseController
+	enc       *json.Encoder
+	opts      StreamOptions
+	count     int
+	pending   int
+	lastFlush time.Time
+}
+
+func newStreamWriter(w http.ResponseWriter, r *http.Request, opts StreamOptions) *streamWriter {
+	return &streamWriter{
+		w:         w,
+		r:         r,
+		rc:        http.NewResponseController(w),
+		enc:       json.NewEncoder(w),
+		opts:      opts,
+		lastFlush: time.Now(),
+	}
+}
+
+func (sw *streamWriter) begin() {
+	h := sw.w.Header()
+	h.Set("Trailer", StreamStatusTrailer)
+	h.Set("X-Content-Type-Options", "nosniff")
+	if sw.opts.Format == StreamNDJSON {
+		h.Set("Content-Type", "application/x-ndjson")
+	} else {
+		h.Set("Content-Type", "application/json")
+	}
+	sw.w.WriteHeader(http.StatusOK)
+
+	if sw.opts.Format == StreamJSONArray {
+		sw.w.Write([]byte(`{"data":[`))
+	}
+	sw.flush()
+}
+
+func (sw *streamWriter) item(v any) error {
+	if sw.opts.Format == StreamJSONArray {
+		body, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if sw.count > 0 {
+			sw.w.Write([]byte(","))
+		}
+		if _, err := sw.w.Write(body); err != nil {
+			return err
+		}
+	} else if err := sw.enc.Encode(v); err != nil {
+		return err
+	}
+
+	sw.count++
+	sw.pending++
+	if sw.pending >= sw.opts.FlushEvery || time.Since(sw.lastFlush) >= sw.opts.FlushInterval {
+		sw.flush()
+	}
+	return nil
+}
+
+func (sw *streamWriter) end() {
+	if sw.opts.Format == StreamJSONArray {
+		sw.w.Write([]byte(`],"count":` + strconv.Itoa(sw.count) + `}`))
+	}
+	sw.w.Header().Set(StreamStatusTrailer, "ok")
+	sw.flush()
+}
+
+func (sw *streamWriter) fail(err error) {
+	_, data := errors.NewResponse(sw.r, err)
+	if sw.opts.Format == StreamJSONArray {
+		body, _ := json.Marshal(data.Error)
+		sw.w.Write([]byte(`],"error":`))
+		sw.w.Write(body)
+		sw.w.Write([]byte(`}`))
+	} else {
+		sw.enc.Encode(data)
+	}
+	sw.w.Header().Set(StreamStatusTrailer, "error")
+	sw.flush()
+}
+
+func (sw *streamWriter) flush() {
+	_ = sw.rc.Flush()
+	sw.pending = 0
+	sw.lastFlush = time.Now()
+}
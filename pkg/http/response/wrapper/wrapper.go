@@ -38,22 +38,61 @@ type Scroll struct {
 	Data interface{} `json:"data"`
 }
 
-type Wrapper struct{}
+// DataOf is Data with a typed payload, for decoding responses on the client side.
+type DataOf[T any] struct {
+	Data T `json:"data"`
+}
+
+// ListOf is List with typed items.
+type ListOf[T any] struct {
+	Data  []T `json:"data"`
+	Count int `json:"count"`
+}
+
+// PaginationOf is Pagination with typed items.
+type PaginationOf[T any] struct {
+	Data []T `json:"data"`
+	DataRange
+}
+
+// ScrollOf is Scroll with typed items.
+type ScrollOf[T any] struct {
+	Meta *Meta `json:"meta"`
+	Data []T   `json:"data"`
+}
+
+type Wrapper struct {
+	cache *CachePolicy
+}
 
 func NewWrapper() *Wrapper {
 	return &Wrapper{}
 }
 
-func (rw *Wrapper) response(w http.ResponseWriter, data interface{}) {
+func (rw *Wrapper) response(w http.ResponseWriter, r *http.Request, data interface{}) {
+	rw.responseStatus(w, r, http.StatusOK, data)
+}
+
+func (rw *Wrapper) responseStatus(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		w.WriteHeader(status)
+		return
+	}
 	if data != nil {
 		body, err := json.Marshal(data)
 		if err != nil {
 			errors.SetError(w, nil, err)
 			return
 		}
+		if status == http.StatusOK && rw.cache != nil && rw.cache.apply(w, r, body) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
+		w.WriteHeader(status)
 		w.Write(body)
+	} else if status != http.StatusOK {
+		w.WriteHeader(status)
 	}
 }
 
@@ -64,7 +103,7 @@ func (rw *Wrapper) Empty(ctrlFunc func(w http.ResponseWriter, r *http.Request) e
 			errors.SetError(w, r, err)
 			return
 		}
-		rw.response(w, map[string]interface{}{"message": "ok"})
+		rw.response(w, r, map[string]interface{}{"message": "ok"})
 	}
 }
 
@@ -76,7 +115,8 @@ func (rw *Wrapper) Plain(ctrlFunc func(w http.ResponseWriter, r *http.Request) (
 			errors.SetError(w, r, err)
 			return
 		}
-		rw.response(w, data)
+		data, status := resolve(w, data)
+		rw.responseStatus(w, r, status, data)
 	}
 }
 
@@ -87,7 +127,8 @@ func (rw *Wrapper) Data(ctrlFunc func(w http.ResponseWriter, r *http.Request) (i
 			errors.SetError(w, r, err)
 			return
 		}
-		rw.response(w, Data{
+		data, status := resolve(w, data)
+		rw.responseStatus(w, r, status, Data{
 			Data: data,
 		})
 	}
@@ -100,6 +141,7 @@ func (rw *Wrapper) DataList(ctrlFunc func(r *http.Request) (interface{}, error))
 			errors.SetError(w, r, err)
 			return
 		}
+		data, status := resolve(w, data)
 		var listCount int
 		switch reflect.TypeOf(data).Kind() {
 		case reflect.Slice:
@@ -107,7 +149,7 @@ func (rw *Wrapper) DataList(ctrlFunc func(r *http.Request) (interface{}, error))
 		default:
 			panic("return data does not common")
 		}
-		rw.response(w, List{
+		rw.responseStatus(w, r, status, List{
 			Data:  data,
 			Count: listCount,
 		})
@@ -121,7 +163,8 @@ func (rw *Wrapper) DataPages(ctrlFunc func(w http.ResponseWriter, r *http.Reques
 			errors.SetError(w, r, err)
 			return
 		}
-		rw.response(w, Pagination{
+		data, status := resolve(w, data)
+		rw.responseStatus(w, r, status, Pagination{
 			Data:      data,
 			DataRange: *params,
 		})
@@ -135,7 +178,8 @@ func (rw *Wrapper) DataScroll(ctrlFunc func(w http.ResponseWriter, r *http.Reque
 			errors.SetError(w, r, err)
 			return
 		}
-		rw.response(w, Scroll{
+		data, status := resolve(w, data)
+		rw.responseStatus(w, r, status, Scroll{
 			Data: data,
 			Meta: meta,
 		})
@@ -149,7 +193,8 @@ func (rw *Wrapper) Raw(ctrlFunc func(w http.ResponseWriter, r *http.Request) (in
 			errors.SetError(w, r, err)
 			return
 		}
-		w.WriteHeader(http.StatusOK)
+		data, status := resolve(w, data)
+		w.WriteHeader(status)
 		fmt.Fprint(w, data)
 	}
 }
@@ -1,6 +1,9 @@
 package server
 
-import "net"
+import (
+	"net"
+	"time"
+)
 
 // Option - настройки HTTP-сервера.
 type Option func(*Server)
@@ -11,3 +14,25 @@ func Port(port string) Option {
 		s.server.Addr = net.JoinHostPort("", port)
 	}
 }
+
+// ReadTimeout - таймаут чтения запроса.
+func ReadTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.server.ReadTimeout = timeout
+	}
+}
+
+// WriteTimeout - таймаут записи ответа.
+// Долгоживущие потоки (sse) снимают его для своего соединения сами.
+func WriteTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.server.WriteTimeout = timeout
+	}
+}
+
+// ShutdownTimeout - время на корректное завершение активных запросов.
+func ShutdownTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeout = timeout
+	}
+}
@@ -2,14 +2,16 @@ package utils
 
 import (
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/mlplabs/common-go-pkg/pkg/http/jwtutils"
 )
 
 type TokenPair struct {
@@ -50,6 +52,10 @@ func CreateTokenPair(claims map[string]any, secretKey string,
 		accessClaims[k] = v
 		refreshClaims[k] = v
 	}
+	accessClaims["typ"] = jwtutils.TokenTypeAccess
+	accessClaims["jti"] = uuid.NewString()
+	refreshClaims["typ"] = jwtutils.TokenTypeRefresh
+	refreshClaims["jti"] = uuid.NewString()
 
 	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
@@ -66,22 +72,22 @@ func CreateTokenPair(claims map[string]any, secretKey string,
 	return &TokenPair{accessTokenString, "bearer", tokenExpiresSec, refreshTokenSting}, err
 }
 
-func ReadToken(secretKey string, tokenString string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-
-		return []byte(secretKey), nil
-	})
+// ReadToken reads an HMAC signed token, opts add claim checks, see jwtutils.WithValidation.
+func ReadToken(secretKey string, tokenString string, opts ...jwtutils.Option) (*jwt.Token, error) {
+	v, err := jwtutils.NewValidator([]byte(secretKey), opts...)
 	if err != nil {
 		return nil, err
 	}
-	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return token, nil
-	}
+	return v.Parse(tokenString)
+}
 
-	return nil, err
+// ReadTokenWithKeySet reads a token signed by one of keys, e.g. jwtutils.JWKS.
+func ReadTokenWithKeySet(keys jwtutils.KeySet, tokenString string, opts ...jwtutils.Option) (*jwt.Token, error) {
+	v, err := jwtutils.NewKeySetValidator(keys, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return v.Parse(tokenString)
 }
 
 func ReadTokenUnverified(tokenString string) (*jwt.Token, []string, error) {
@@ -93,21 +99,9 @@ func ReadTokenUnverified(tokenString string) (*jwt.Token, []string, error) {
 	return token, parts, nil
 }
 
-func TokenValid(r *http.Request, secretKey string) error {
-	tokenString := ExtractToken(r)
-
-	_, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-
-		return []byte(secretKey), nil
-	})
-	if err != nil {
-		return err
-	}
-
-	return nil
+func TokenValid(r *http.Request, secretKey string, opts ...jwtutils.Option) error {
+	_, err := ReadToken(secretKey, ExtractToken(r), opts...)
+	return err
 }
 
 func ExtractToken(r *http.Request) string {